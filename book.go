@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli"
+)
+
+// The exercises of Competitive Programming by Steven and Felix Halim are
+// imported from uHunt as lists named cp3-ch1, cp3-ch2 and so on.
+
+type bookInfo struct {
+	Edition  int
+	Chapters []string
+}
+
+func chapterList(edition, chapter int) string {
+	return fmt.Sprintf("cp%d-ch%d", edition, chapter)
+}
+
+func loadBook() bookInfo {
+	var book bookInfo
+	readYaml(bookFile, &book)
+	if book.Edition == 0 {
+		panic("no book imported yet, run `uva book import`")
+	}
+	return book
+}
+
+// uhuntBook downloads the exercises of an edition. Chapters have sections,
+// which have subsections like ["Super Easy", 11044, -11172], where starred
+// problems are negative.
+func uhuntBook(edition int) (titles []string, problems [][]int) {
+	var chapters []struct {
+		Title string
+		Arr   []struct {
+			Title string
+			Arr   [][]interface{}
+		}
+	}
+	if err := uhuntGet(fmt.Sprintf("/cpbook/%d", edition), "Downloading the exercises", &chapters); err != nil {
+		panic(err)
+	}
+	for _, chapter := range chapters {
+		var pids []int
+		for _, section := range chapter.Arr {
+			for _, subsection := range section.Arr {
+				for _, v := range subsection {
+					f, ok := v.(float64)
+					if !ok {
+						// the title
+						continue
+					}
+					pid := int(f)
+					if pid < 0 {
+						pid = -pid
+					}
+					if !containsInt(pids, pid) {
+						pids = append(pids, pid)
+					}
+				}
+			}
+		}
+		titles = append(titles, chapter.Title)
+		problems = append(problems, pids)
+	}
+	if len(titles) == 0 {
+		panic(fmt.Sprintf("uHunt has no exercises for edition %d", edition))
+	}
+	return
+}
+
+func bookImport(c *cli.Context) {
+	edition := c.Int("edition")
+	titles, problems := uhuntBook(edition)
+	lists := loadLists()
+	total := 0
+	for i, pids := range problems {
+		lists[chapterList(edition, i+1)] = pids
+		total += len(pids)
+	}
+	writeYaml(listsFile, lists)
+	writeYaml(bookFile, bookInfo{edition, titles})
+	fmt.Printf("Imported %d problems of %d chapters as lists %s to %s\n",
+		total, len(titles), chapterList(edition, 1), chapterList(edition, len(titles)))
+}
+
+// solvedProblems returns the IDs of the problems accepted by the judge or in
+// the local history. The judge's solved set comes from uHunt, and is skipped
+// when it can't be downloaded.
+func solvedProblems() map[int]bool {
+	solved := make(map[int]bool)
+	for _, s := range loadHistory() {
+		if s.Verdict == "Accepted" {
+			solved[s.Problem] = true
+		}
+	}
+	if !loggedIn() {
+		return solved
+	}
+	func() {
+		defer func() {
+			if err := recover(); err != nil {
+				cprintf(magenta, 0, "Using the local history only: %s\n", err)
+			}
+		}()
+		accepted, _ := uhuntUserInfo(loadLoginInfo().Username).solved()
+		for _, p := range loadProblems() {
+			if accepted[p.TrueID] {
+				solved[p.ID] = true
+			}
+		}
+	}()
+	return solved
+}
+
+func bookProgress(c *cli.Context) {
+	loadConfig()
+	book := loadBook()
+	lists := loadLists()
+	solved := solvedProblems()
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	for i, title := range book.Chapters {
+		pids := lists[chapterList(book.Edition, i+1)]
+		n := 0
+		for _, pid := range pids {
+			if solved[pid] {
+				n++
+			}
+		}
+		const barWidth = 20
+		filled := 0
+		if len(pids) != 0 {
+			filled = n * barWidth / len(pids)
+		}
+		bar := colored(strings.Repeat("█", filled), cyan, 0) + strings.Repeat("░", barWidth-filled)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d/%d\n", colored(chapterList(book.Edition, i+1), yellow, bold), title, bar, n, len(pids))
+	}
+	w.Flush()
+}
+
+// bookNext starts the first unsolved problem of a chapter, or of the first
+// chapter not finished yet.
+func bookNext(c *cli.Context) {
+	book := loadBook()
+	lists := loadLists()
+	solved := solvedProblems()
+	chapters := make([]int, len(book.Chapters))
+	for i := range chapters {
+		chapters[i] = i + 1
+	}
+	if c.NArg() != 0 {
+		arg := strings.TrimPrefix(c.Args().First(), fmt.Sprintf("cp%d-ch", book.Edition))
+		chapter, err := strconv.Atoi(arg)
+		if err != nil || chapter < 1 || chapter > len(book.Chapters) {
+			panic(fmt.Sprintf("chapter should be 1 to %d", len(book.Chapters)))
+		}
+		chapters = []int{chapter}
+	}
+
+	loadConfig()
+	lang := defaultLang(c.String("lang"))
+	problems := loadProblems()
+	for _, chapter := range chapters {
+		for _, pid := range lists[chapterList(book.Edition, chapter)] {
+			info, ok := problems[pid]
+			if solved[pid] || !ok || !info.available() {
+				continue
+			}
+			fmt.Printf("Chapter %d, %s: %s\n", chapter, book.Chapters[chapter-1], colored(fmt.Sprintf("%d - %s", info.ID, info.Title), yellow, bold))
+			if name := info.getFileName(lang); exists(name) {
+				fmt.Printf("Continue with %s\n", colored(name, yellow, underline))
+				return
+			}
+			touchProblem(pid, lang, c.Bool("samples"), false)
+			return
+		}
+	}
+	fmt.Println(colored(yes+" All problems solved", cyan, bold))
+}
@@ -1,103 +1,294 @@
 package main
 
 import (
+	"bytes"
 	"encoding/gob"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/cookiejar"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 
 	"golang.org/x/net/publicsuffix"
 )
 
 var (
-	dataPath         = os.Getenv("HOME") + "/.local/share/uva-cli/"
-	pdfPath          = dataPath + "pdf/"
-	testDataPath     = dataPath + "test-data/"
-	loginInfoFile    = dataPath + "login-info.gob"
+	dataPath     = os.Getenv("HOME") + "/.local/share/uva-cli/"
+	pdfPath      = dataPath + "pdf/"
+	testDataPath = dataPath + "test-data/"
+	profilesPath = dataPath + "profiles/"
+	profileFile  = dataPath + "profile"
+	// the single login of old versions, moved to the default profile
+	legacyLoginFile  = dataPath + "login-info.gob"
 	problemsInfoFile = dataPath + "problems-info.gob"
+	templatePath     = dataPath + "templates/"
+	// topic tags of problems, by problem ID
+	tagsFile  = dataPath + "tags.yml"
+	listsFile = dataPath + "lists.yml"
+	notesPath = dataPath + "notes/"
+	// the submissions made with `uva submit`, a JSON object per line
+	historyFile = dataPath + "history.jsonl"
+	// the chapter titles of the imported book
+	bookFile = dataPath + "book.yml"
 )
 
+// loadProblems returns all problems of UVa, downloading the list on first
+// use.
+func loadProblems() map[int]problemInfo {
+	return uvaJudge.problems()
+}
+
 func getProblemInfo(pid int) problemInfo {
-	var problems map[int]problemInfo
-	if exists(problemsInfoFile) {
-		f, err := os.Open(problemsInfoFile)
-		if err != nil {
-			panic(err)
+	r, ok := judgeFor(pid).problems()[pid]
+	if !ok {
+		panic("problem not found")
+	}
+	return r
+}
+
+// isPdf checks the header only, as valid files may have bytes after %%EOF.
+// Downloads are written once complete, so they are not truncated.
+func isPdf(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("%PDF-"))
+}
+
+func isHTML(data []byte, contentType string) bool {
+	return strings.Contains(contentType, "html") ||
+		strings.HasPrefix(http.DetectContentType(data), "text/html")
+}
+
+// validStatement detects corrupt cache files, like error pages saved as PDF
+// by older versions.
+func validStatement(file string) bool {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		panic(err)
+	}
+	if strings.HasSuffix(file, ".html") {
+		return isHTML(data, "")
+	}
+	return isPdf(data)
+}
+
+// getStatement returns the cached description of a problem. It is usually a
+// PDF, but some problems only have an HTML page.
+func getStatement(info problemInfo) string {
+	pdfFile := pdfPath + info.getFileName("pdf")
+	htmlFile := pdfPath + info.getFileName("html")
+	for _, file := range []string{pdfFile, htmlFile} {
+		if exists(file) {
+			if validStatement(file) {
+				return file
+			}
+			if err := os.Remove(file); err != nil {
+				panic(err)
+			}
 		}
-		defer f.Close()
-		if err := gob.NewDecoder(f).Decode(&problems); err != nil {
-			panic(err)
+	}
+
+	msg := "Downloading " + info.Title
+	var firstErr error
+	for _, u := range judgeFor(info.ID).statementURLs(info) {
+		data, contentType, err := fetch(u, msg)
+		file := ""
+		switch {
+		case err != nil:
+		case isPdf(data):
+			file = pdfFile
+		case isHTML(data, contentType):
+			file = htmlFile
+		default:
+			err = fmt.Errorf("%s is not a PDF file", u)
 		}
-	} else {
-		problems = crawlProblemsInfo()
-		f, err := os.Create(problemsInfoFile)
-		if err != nil {
-			panic(err)
+		if file != "" {
+			if err := ioutil.WriteFile(file, data, 0644); err != nil {
+				panic(err)
+			}
+			return file
+		}
+		if firstErr == nil {
+			firstErr = err
 		}
-		defer f.Close()
-		if err := gob.NewEncoder(f).Encode(problems); err != nil {
+	}
+	panic(fmt.Errorf("problem description not found: %s", firstErr))
+}
+
+// readGob decodes values from file in order.
+func readGob(file string, values ...interface{}) {
+	f, err := os.Open(file)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	dec := gob.NewDecoder(f)
+	for _, v := range values {
+		if err = dec.Decode(v); err != nil {
 			panic(err)
 		}
 	}
-	r, ok := problems[pid]
-	if !ok {
-		panic("problem not found")
+}
+
+func writeGob(file string, values ...interface{}) {
+	f, err := os.Create(file)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	enc := gob.NewEncoder(f)
+	for _, v := range values {
+		if err = enc.Encode(v); err != nil {
+			panic(err)
+		}
 	}
-	return r
 }
 
 func getTestData(pid int) (input string, output string) {
 	testDataFile := testDataPath + getProblemInfo(pid).getFileName("gob")
 	if exists(testDataFile) {
-		f, err := os.Open(testDataFile)
-		if err != nil {
-			panic(err)
-		}
-		defer f.Close()
-		dec := gob.NewDecoder(f)
-		if err = dec.Decode(&input); err != nil {
-			panic(err)
-		}
-		if err = dec.Decode(&output); err != nil {
-			panic(err)
-		}
+		readGob(testDataFile, &input, &output)
 	} else {
 		input, output = crawlTestData(pid)
-		f, err := os.Create(testDataFile)
-		if err != nil {
-			panic(err)
+		writeGob(testDataFile, input, output)
+	}
+	return
+}
+
+// getSamples returns the sample input and output in the problem description.
+func getSamples(info problemInfo) (input string, output string) {
+	samplesFile := testDataPath + info.getFileName("sample.gob")
+	if exists(samplesFile) {
+		readGob(samplesFile, &input, &output)
+	} else {
+		input, output = extractSamples(statementText(getStatement(info)))
+		// nothing found may be a bug of the extraction, so try again next time
+		if input != "" || output != "" {
+			writeGob(samplesFile, input, output)
 		}
-		defer f.Close()
-		enc := gob.NewEncoder(f)
-		if err = enc.Encode(input); err != nil {
-			panic(err)
+	}
+	return
+}
+
+const defaultProfile = "default"
+
+// selectedProfile is set by the --profile flag.
+var selectedProfile string
+
+var profileName = regexp.MustCompile(`^[\w-]+$`)
+
+func checkProfileName(name string) {
+	if !profileName.MatchString(name) {
+		panic(fmt.Sprintf("invalid profile name %q, use letters, digits, - and _", name))
+	}
+}
+
+// currentProfile returns the profile chosen by --profile, or the saved one.
+func currentProfile() string {
+	if selectedProfile != "" {
+		checkProfileName(selectedProfile)
+		return selectedProfile
+	}
+	return savedProfile()
+}
+
+// savedProfile returns the profile chosen by `uva user --switch`.
+func savedProfile() string {
+	if data, err := ioutil.ReadFile(profileFile); err == nil {
+		if name := strings.TrimSpace(string(data)); name != "" {
+			return name
 		}
-		if err = enc.Encode(output); err != nil {
+	}
+	return defaultProfile
+}
+
+func loginInfoFile(profile string) string {
+	return profilesPath + profile + ".gob"
+}
+
+func switchProfile(profile string) {
+	checkProfileName(profile)
+	if err := ioutil.WriteFile(profileFile, []byte(profile+"\n"), 0644); err != nil {
+		panic(err)
+	}
+}
+
+func listProfiles() []string {
+	files, err := filepath.Glob(profilesPath + "*.gob")
+	if err != nil {
+		panic(err)
+	}
+	profiles := make([]string, len(files))
+	for i, file := range files {
+		profiles[i] = strings.TrimSuffix(filepath.Base(file), ".gob")
+	}
+	sort.Strings(profiles)
+	return profiles
+}
+
+func migrateLoginInfo() {
+	if exists(legacyLoginFile) && !exists(loginInfoFile(defaultProfile)) {
+		if err := os.Rename(legacyLoginFile, loginInfoFile(defaultProfile)); err != nil {
 			panic(err)
 		}
 	}
-	return
+	// a development version saved remembered passwords in plain text in the
+	// profiles, rewrite them without
+	for _, profile := range listProfiles() {
+		var plain struct{ Username, Password string }
+		readGob(loginInfoFile(profile), &plain)
+		if plain.Password != "" {
+			var info loginInfo
+			readGob(loginInfoFile(profile), &info)
+			saveLoginInfo(profile, info)
+		}
+	}
 }
 
-func loadLoginInfo() loginInfo {
-	if !exists(loginInfoFile) {
-		panic("you are not logged in yet")
-	}
+func loggedIn() bool {
+	return exists(loginInfoFile(currentProfile()))
+}
 
-	f, err := os.Open(loginInfoFile)
+// saveLoginInfo writes the session cookies to a file only the user can read.
+func saveLoginInfo(profile string, info loginInfo) {
+	f, err := os.OpenFile(loginInfoFile(profile), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		panic(err)
 	}
 	defer f.Close()
-	var info loginInfo
-	if err := gob.NewDecoder(f).Decode(&info); err != nil {
+	// an existing file keeps its mode on open
+	if err := f.Chmod(0600); err != nil {
+		panic(err)
+	}
+	if err := gob.NewEncoder(f).Encode(info); err != nil {
 		panic(err)
 	}
+}
+
+// loadLoginInfo loads the current profile.
+func loadLoginInfo() loginInfo {
+	return loadProfile(currentProfile())
+}
+
+// loadProfile loads the login info of a profile, and installs its cookies.
+func loadProfile(profile string) loginInfo {
+	if !exists(loginInfoFile(profile)) {
+		if profile == defaultProfile {
+			panic("you are not logged in yet")
+		}
+		panic(fmt.Sprintf("you are not logged in with profile %s yet", profile))
+	}
+	var info loginInfo
+	readGob(loginInfoFile(profile), &info)
 	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
 	if err != nil {
 		panic(err)
 	}
-	jar.SetCookies(uvaURL, info.Cookies)
+	if site := judgeByName(info.judge()).site(); site != nil {
+		jar.SetCookies(site, info.Cookies)
+	}
 	http.DefaultClient.Jar = jar
 	return info
 }
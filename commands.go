@@ -3,10 +3,12 @@ package main
 import (
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
-	"os/exec"
+	"os/signal"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -14,49 +16,67 @@ import (
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
-	humanize "github.com/dustin/go-humanize"
 	"github.com/urfave/cli"
 )
 
 func user(c *cli.Context) {
-	if c.Bool("l") {
-		username := login()
+	if p := c.String("profile"); p != "" {
+		selectedProfile = p
+	}
+	profile := currentProfile()
+	switch {
+	case c.Bool("l"):
+		loadConfig()
+		j := judgeByName(c.String("judge"))
+		if j.site() == nil {
+			panic(fmt.Sprintf("judge %s needs no login, choose another with --judge", j.name()))
+		}
+		firstLogin := len(listProfiles()) == 0
+		username := login(j, profile, c.String("username"), c.Bool("password-stdin"), c.Bool("remember"))
 		fmt.Println("Successfully login as", colored(username, yellow, 1))
-	} else if c.Bool("L") {
-		if err := os.Remove(loginInfoFile); err != nil {
+		if firstLogin {
+			switchProfile(profile)
+		} else if profile != savedProfile() {
+			fmt.Printf("Run `uva user --switch %s` to use this profile by default\n", profile)
+		}
+	case c.Bool("L"):
+		if !loggedIn() {
+			panic("you are not logged in yet")
+		}
+		loadConfig()
+		info := loadLoginInfo()
+		if err := os.Remove(loginInfoFile(profile)); err != nil {
 			panic(err)
 		}
-	} else {
+		logoutPassword(profile, info.Username)
+	case c.String("switch") != "":
+		name := c.String("switch")
+		checkProfileName(name)
+		if !exists(loginInfoFile(name)) {
+			panic(fmt.Sprintf("profile %s does not exist, create it with `uva user -l --profile %s`", name, name))
+		}
+		switchProfile(name)
+		fmt.Println("Switched to profile", colored(name, yellow, bold))
+	case c.Bool("list"):
+		for _, name := range listProfiles() {
+			var info loginInfo
+			readGob(loginInfoFile(name), &info)
+			mark := " "
+			if name == profile {
+				mark = colored(yes, cyan, bold)
+			}
+			fmt.Printf("%s %s\t%s\t%s\n", mark, name, colored(info.Username, yellow, 0), info.judge())
+		}
+	default:
 		fmt.Println("You are now logged in as", colored(loadLoginInfo().Username, yellow, bold))
 	}
 }
 
-func printPdf(file string, info problemInfo) {
-	pdf, err := exec.Command("pdftotext", file, "-").Output()
-	if err != nil {
-		panic(err)
+func browser() []string {
+	if b := strings.Fields(os.Getenv("BROWSER")); len(b) != 0 {
+		return b
 	}
-	description := string(pdf)
-	title := fmt.Sprintf("%d - %s", info.ID, info.Title)
-	padding := strings.Repeat(" ", (108-len(title))/2)
-	cprintf(white, 1, "%s%s\n\n", padding, title)
-
-	const indent = "       "
-	cprintf(white, 1, "Statistics\n")
-	fmt.Printf(indent+"* Rate: %.1f %%\n", info.Percentage)
-	accepted := humanize.Bytes(uint64(float32(info.TotalSubmissions) * info.Percentage / 100))
-	fmt.Printf(indent+"* Total Accepted: %s\n", accepted[:len(accepted)-1])
-	submissions := humanize.Bytes(uint64(info.TotalSubmissions))
-	fmt.Printf(indent+"* Total Submissions: %s\n\n", submissions[:len(submissions)-1])
-
-	cprintf(white, 1, "Description\n")
-	// indentation
-	description = strings.Replace(description, "\n", "\n"+indent, -1)
-	for _, s := range []string{"Input", "Output", "Sample Input", "Sample Output"} {
-		description = strings.Replace(description, indent+s, colored(s, white, bold), 1)
-	}
-	description = indent + strings.TrimSpace(description)
-	fmt.Println(description)
+	return systemOpener()
 }
 
 func show(c *cli.Context) {
@@ -67,21 +87,77 @@ func show(c *cli.Context) {
 	if err != nil {
 		panic(err)
 	}
+	loadConfig()
 	info := getProblemInfo(pid)
-	pdfFile := pdfPath + info.getFileName("pdf")
-	if !exists(pdfFile) {
-		download(fmt.Sprintf("%s/external/%d/p%d.pdf", baseURL, pid/100, pid), pdfFile, "Downloading "+info.Title)
-	}
+	switch {
+	case c.Bool("web"):
+		openDetached(browser(), info.url())
+		return
+	case c.Bool("udebug"):
+		openDetached(browser(), judgeFor(pid).udebugURL(pid))
+		return
+	}
+	file := getStatement(info)
 
 	if c.Bool("g") {
-		if err := exec.Command("evince", pdfFile).Run(); err != nil {
-			panic(err)
+		// $UVA_PDF_VIEWER overrides the config
+		viewer := strings.Fields(os.Getenv("UVA_PDF_VIEWER"))
+		if len(viewer) == 0 {
+			viewer = config.Viewer
 		}
-	} else {
-		printPdf(pdfFile, info)
+		if len(viewer) == 0 {
+			viewer = systemOpener()
+		}
+		openDetached(viewer, file)
+		return
+	}
+	text := statementText(file)
+	switch format := c.String("format"); format {
+	case "":
+		width, _ := terminalSize()
+		page(renderStatement(info, text, width) + renderNotes(pid))
+	case "text":
+		colorEnabled = false
+		width, _ := terminalSize()
+		fmt.Print(renderStatement(info, text, width) + renderNotes(pid))
+	case "md", "markdown":
+		fmt.Print(renderMarkdown(info, text))
+	case "html":
+		fmt.Print(renderHTML(info, text))
+	default:
+		panic("unknown format " + format + ", use md, html or text")
 	}
 }
 
+// renderSource fills the template of a language with the problem's metadata.
+// Templates use placeholders named like the ones of the commands in config,
+// e.g. {pid}. Other braces are code and left alone. An empty file is created
+// if there is no template.
+func renderSource(info problemInfo, lang string) []byte {
+	file := templatePath + "template." + lang
+	tmpl, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		panic(err)
+	}
+	author := config.Author
+	if author == "" && loggedIn() {
+		author = loadLoginInfo().Username
+	}
+	if author == "" {
+		author = os.Getenv("USER")
+	}
+	r := strings.NewReplacer(
+		"{pid}", strconv.Itoa(info.ID),
+		"{title}", info.Title,
+		"{url}", info.url(),
+		"{date}", time.Now().Format("2006-01-02"),
+		"{author}", author,
+	)
+	return []byte(r.Replace(string(tmpl)))
+}
+
 func touch(c *cli.Context) {
 	if c.NArg() == 0 {
 		panic("problem ID required")
@@ -90,30 +166,60 @@ func touch(c *cli.Context) {
 	if err != nil {
 		panic(err)
 	}
-	lang := c.String("lang")
+	loadConfig()
+	touchProblem(pid, c.String("lang"), c.Bool("samples"), c.Bool("force"))
+}
+
+// defaultLang returns lang, or the default extension in config.
+func defaultLang(lang string) string {
 	if lang == "" {
-		loadConfig()
 		lang = config.Lang
 		if lang == "" {
 			lang = "cc"
 		}
 	}
-	name := getProblemInfo(pid).getFileName(lang)
-	f, err := os.Create(name)
-	if err != nil {
-		panic(err)
-	}
-	f.Close()
+	return lang
+}
+
+// touchProblem creates the source file of a problem from the template of
+// the language, and the sample files if samples is set.
+func touchProblem(pid int, lang string, samples, force bool) {
+	lang = defaultLang(lang)
+	info := getProblemInfo(pid)
+	name := info.getFileName(lang)
+	writeNewFile(name, renderSource(info, lang), force)
 	fmt.Printf("Created %s\n", colored(name, yellow, underline))
+
+	if samples {
+		input, output := getSamples(info)
+		if input == "" && output == "" {
+			cprintf(magenta, bold, no+" No sample found in the problem description\n")
+			return
+		}
+		stem := strings.TrimSuffix(name, "."+lang)
+		writeNewFile(stem+".in", []byte(input), force)
+		writeNewFile(stem+".ans", []byte(output), force)
+		fmt.Printf("Created %s and %s\n", colored(stem+".in", yellow, underline), colored(stem+".ans", yellow, underline))
+	}
 }
 
-func submit(problemID int, file string, lang int) string {
-	category := problemID / 100
-	info := getProblemInfo(problemID)
-	problemID = info.TrueID
+func (j *onlineJudge) submit(info problemInfo, file, ext string) string {
+	var lang int
+	switch ext {
+	case "c":
+		lang = ansic
+	case "java":
+		lang = java
+	case "cc", "cpp":
+		lang = cpp
+	case "pas":
+		lang = pascal
+	case "py":
+		lang = python3
+	}
 	form := url.Values{
-		"problemid": {strconv.Itoa(problemID)},
-		"category":  {strconv.Itoa(category)},
+		"problemid": {strconv.Itoa(info.TrueID)},
+		"category":  {strconv.Itoa(info.ID / 100)},
 		"language":  {strconv.Itoa(lang)},
 	}
 	code, err := ioutil.ReadFile(file)
@@ -128,64 +234,147 @@ func submit(problemID int, file string, lang int) string {
 	}
 	defer func() { http.DefaultClient.CheckRedirect = nil }()
 	defer spin("Sending code to judge")()
-	resp, err := http.PostForm(baseURL+
+	resp, err := http.PostForm(j.baseURL+
 		"/index.php?option=com_onlinejudge&Itemid=8&page=save_submission", form)
 	if err != nil {
 		panic(err)
 	}
 	resp.Body.Close()
-	location := resp.Header["Location"][0]
+	// The judge redirects to a page with the submission ID, or to the
+	// login page if the session is not valid.
+	location := resp.Header.Get("Location")
+	if location == "" {
+		panic(sessionExpired)
+	}
 	sidRegex := regexp.MustCompile(`Submission\+received\+with\+ID\+(\d+)`)
-	submitID := string(sidRegex.FindSubmatch([]byte(location))[1])
-	return submitID
+	match := sidRegex.FindStringSubmatch(location)
+	if match == nil {
+		// the judge explains other errors in mosmsg
+		if u, err := url.Parse(location); err == nil && u.Query().Get("mosmsg") != "" {
+			panic("submission rejected: " + u.Query().Get("mosmsg"))
+		}
+		panic(sessionExpired)
+	}
+	return match[1]
 }
 
-func getResult(submitID string) (result, runTime string) {
-	resp, err := http.Get(baseURL + "/index.php?option=com_onlinejudge&Itemid=9")
+// notFoundError is returned by the result of a judge that does not know the
+// submission. Unlike network errors, it is not worth retrying.
+type notFoundError string
+
+func (e notFoundError) Error() string {
+	return string(e)
+}
+
+// result looks up a submission in the recent submissions of the user.
+// Errors are returned rather than panicking, as they may be transient.
+func (j *onlineJudge) result(submitID string) (result, runTime string, err error) {
+	resp, err := http.Get(j.baseURL + "/index.php?option=com_onlinejudge&Itemid=9")
 	if err != nil {
-		panic(err)
+		return "", "", err
 	}
 	doc, err := goquery.NewDocumentFromResponse(resp)
 	if err != nil {
-		panic(err)
+		return "", "", err
 	}
-	row := doc.Find("#col3_content_wrapper > table:nth-child(3) > tbody > tr:nth-child(2) > td")
-	if row.First().Text() != submitID {
-		panic("not latest submit")
-	}
-	return strings.TrimSpace(row.Eq(3).Text()), row.Eq(5).Text()
+	found := false
+	doc.Find("#col3_content_wrapper > table:nth-child(3) > tbody > tr").EachWithBreak(func(i int, tr *goquery.Selection) bool {
+		row := tr.Find("td")
+		if strings.TrimSpace(row.First().Text()) != submitID {
+			return true
+		}
+		found = true
+		result, runTime = strings.TrimSpace(row.Eq(3).Text()), strings.TrimSpace(row.Eq(5).Text())
+		return false
+	})
+	if !found {
+		return "", "", notFoundError(fmt.Sprintf("submission %s not found in the recent submissions", submitID))
+	}
+	return result, runTime, nil
 }
 
-func submitAndShowResult(c *cli.Context) {
-	if c.NArg() == 0 {
-		panic("filename required")
+// pendingResults are shown while the submission is being judged.
+var pendingResults = map[string]bool{
+	"":               true,
+	"Received":       true,
+	"In judge queue": true,
+	"Sent to judge":  true,
+	"Compiling":      true,
+	"Linking":        true,
+	"Running":        true,
+}
+
+const defaultResultTimeout = 5 * time.Minute
+
+// resultTimeout returns the --timeout flag, or result_timeout in config.
+func resultTimeout(c *cli.Context) time.Duration {
+	if s := c.String("timeout"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			panic(fmt.Sprintf("invalid timeout %q, use a value like 90s or 5m", s))
+		}
+		return d
 	}
-	file := c.Args().First()
-	pid, _, ext := parseFilename(file)
-	var lang int
-	switch ext {
-	case "c":
-		lang = ansic
-	case "java":
-		lang = java
-	case "cc", "cpp":
-		lang = cpp
-	case "pas":
-		lang = pascal
-	case "py":
-		lang = python3
+	if config.ResultTimeout != "" {
+		d, _ := time.ParseDuration(config.ResultTimeout)
+		return d
 	}
-	sid := submit(pid, file, lang)
+	return defaultResultTimeout
+}
+
+// submittedGrace is how long a new submission may be missing from the
+// judge's list.
+const submittedGrace = 30 * time.Second
+
+// waitResult polls the judge until the submission has a verdict. The delay
+// between polls doubles up to 16 seconds, and network errors are retried
+// until the timeout. Other errors fail at once, and so does a submission
+// the judge does not know, after grace if it was just submitted. Ctrl-C stops waiting; the
+// submission goes on.
+func waitResult(j judge, sid string, timeout, grace time.Duration) (result, runTime string) {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
 	stop := spin("Waiting for judge result")
-	const judging = "In judge queue"
-	result := judging
-	var runTime string
-	for result == judging {
-		result, runTime = getResult(sid)
-		time.Sleep(1 * time.Second)
+	start := time.Now()
+	deadline := start.Add(timeout)
+	delay := time.Second
+	var err error
+	for {
+		result, runTime, err = j.result(sid)
+		if err == nil && !pendingResults[result] {
+			stop()
+			return
+		}
+		if _, transient := err.(net.Error); err != nil && !transient {
+			if _, notFound := err.(notFoundError); !notFound || time.Since(start) >= grace {
+				stop()
+				panic(err)
+			}
+		}
+		if time.Now().Add(delay).After(deadline) {
+			stop()
+			msg := fmt.Sprintf("no verdict after %s", timeout)
+			if err != nil {
+				msg += fmt.Sprintf(" (%s)", err)
+			}
+			panic(fmt.Sprintf("%s, run `uva status %s` to check later", msg, sid))
+		}
+		select {
+		case <-interrupt:
+			stop()
+			fmt.Printf("Stopped waiting, run `uva status %s` to check later\n", sid)
+			os.Exit(130)
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > 16*time.Second {
+			delay = 16 * time.Second
+		}
 	}
-	stop()
+}
 
+func showResult(result, runTime string) {
 	if result == "Accepted" {
 		cprintf(cyan, bold, "%s Accepted (%ss)\n", yes, runTime)
 	} else {
@@ -193,6 +382,54 @@ func submitAndShowResult(c *cli.Context) {
 	}
 }
 
+func submitAndShowResult(c *cli.Context) {
+	if c.NArg() == 0 {
+		panic("filename required")
+	}
+	loadConfig()
+	file := c.Args().First()
+	pid, _, ext := parseFilename(file)
+	timeout := resultTimeout(c)
+	j := judgeFor(pid)
+	sid := j.submit(getProblemInfo(pid), file, ext)
+	fmt.Println("Submission ID:", colored(sid, yellow, bold))
+	record := submission{ID: sid, Problem: pid, File: file, Time: time.Now()}
+	recordSubmission(record)
+	record.Verdict, record.Runtime = waitResult(j, sid, timeout, submittedGrace)
+	recordSubmission(record)
+	showResult(record.Verdict, record.Runtime)
+	contestNotice(pid)
+}
+
+func status(c *cli.Context) {
+	if c.NArg() == 0 {
+		panic("submission id required")
+	}
+	sid := c.Args().First()
+	if _, err := strconv.Atoi(sid); err != nil {
+		panic("invalid submission id " + sid)
+	}
+	loadConfig()
+	// the judge of the problem, if it was submitted with `uva submit`
+	j := judgeByName("")
+	record, ok := loadHistory()[sid]
+	if ok {
+		j = judgeFor(record.Problem)
+	}
+	if j.site() != nil {
+		loadProfile(profileFor(j))
+	}
+	result, runTime := waitResult(j, sid, resultTimeout(c), 0)
+	if ok && record.Verdict == "" {
+		record.Verdict, record.Runtime = result, runTime
+		recordSubmission(record)
+	}
+	showResult(result, runTime)
+	if ok {
+		contestNotice(record.Problem)
+	}
+}
+
 func testProgram(c *cli.Context) {
 	if c.NArg() == 0 {
 		panic("filename required")
@@ -200,104 +437,161 @@ func testProgram(c *cli.Context) {
 	if c.String("i") == "" && c.String("a") != "" {
 		panic("flag -a must be used with -i")
 	}
+	if c.String("i") != "" && c.Bool("s") {
+		panic("flag -s can not be used with -i")
+	}
+	pkgDir := c.String("package")
+	if pkgDir != "" && (c.String("i") != "" || c.Bool("s")) {
+		panic("flag --package can not be used with -i or -s")
+	}
 	file := c.Args().First()
-	pid, _, ext := parseFilename(file)
+	var pid int
+	var ext string
+	if pkgDir != "" {
+		// the tests come from the package, so any file name will do
+		ext = strings.TrimPrefix(filepath.Ext(file), ".")
+	} else {
+		pid, _, ext = parseFilename(file)
+	}
 
 	loadConfig()
-	if config.Test[ext].Run == nil {
+	if _, ok := config.Test[ext]; !ok {
 		panic("file type not supported, please add compile and run commands to config.yml")
 	}
 
-	compile := renderCmd(config.Test[ext].Compile, file)
+	vars := newCmdVars(file, pid)
+	test := config.Test[ext]
 	// compile source code for non-script languages
-	if compile != nil {
+	if test.Compile != nil {
 		stop := spin("Compiling")
-		out, err := compile.CombinedOutput()
+		out, failed := compileSource(test, vars, "")
 		stop()
-		failed := false
+		if failed {
+			cprintf(red, bold, no+" Compilation Error:\n\n")
+			fmt.Print(out)
+			os.Exit(1)
+		} else if out != "" {
+			cprintf(magenta, bold, no+" Compilation Warning:\n\n")
+			fmt.Print(out)
+		}
+	}
+
+	prob := config.problem(pid)
+	byteByByte := c.Bool("b")
+	// os.Exit skips deferred calls
+	cleanup := func() {}
+	var cases []testCase
+	// only -i without -a runs the program without checking it, an empty
+	// answer is checked like any other
+	check := true
+	if pkgDir != "" {
+		pkg := readPackage(pkgDir)
+		cases = pkg.cases()
+		var sensitive bool
+		prob, sensitive = pkg.config(prob)
+		byteByByte = byteByByte || sensitive
+		dir, err := ioutil.TempDir("", "uva-validator")
 		if err != nil {
-			if _, ok := err.(*exec.ExitError); ok {
-				// a non-zero exit code means compilation failed
-				failed = true
-			} else {
+			panic(err)
+		}
+		cleanup = func() { os.RemoveAll(dir) }
+		defer cleanup()
+		if validator := pkg.validator(dir); validator != nil {
+			prob.Checker, prob.validator = validator, true
+		}
+	} else if inputFile := c.String("i"); inputFile != "" {
+		data, err := ioutil.ReadFile(inputFile)
+		if err != nil {
+			panic(err)
+		}
+		tc := testCase{Input: string(data)}
+		// If the input is provided but there is no answer, we do not compare.
+		check = c.String("a") != ""
+		if answerFile := c.String("a"); answerFile != "" {
+			data, err := ioutil.ReadFile(answerFile)
+			if err != nil {
 				panic(err)
 			}
+			tc.Answer = string(data)
 		}
-		if len(out) != 0 {
-			if failed {
-				cprintf(red, bold, no+" Compilation Error:\n\n")
-				fmt.Print(string(out))
-				os.Exit(1)
-			} else {
-				cprintf(magenta, bold, no+" Compilation Warning:\n\n")
-				fmt.Print(string(out))
+		cases = []testCase{tc}
+	} else if c.Bool("s") {
+		if received, ok := receivedCases(pid); ok {
+			// the tests received from the browser are the samples
+			cases = received
+		} else {
+			input, answer := getSamples(getProblemInfo(pid))
+			if answer == "" {
+				panic("no test case found, please provide one with -i and -a")
 			}
+			cases = []testCase{{Input: input, Answer: answer}}
 		}
-	}
-
-	run := renderCmd(config.Test[ext].Run, file)
-	var answer string
-	if inputFile := c.String("i"); inputFile == "" {
-		// get test case from udebug.com
-		var input string
-		input, answer = getTestData(pid)
-		run.Stdin = strings.NewReader(input)
+	} else if received, ok := receivedCases(pid); ok {
+		cases = received
 	} else {
-		f, err := os.Open(inputFile)
-		if err != nil {
-			panic(err)
+		// get test case from udebug.com
+		input, answer := getTestData(pid)
+		if answer == "" {
+			cprintf(magenta, bold, no+" No test case on udebug.com, using the sample\n")
+			input, answer = getSamples(getProblemInfo(pid))
+		}
+		if answer == "" {
+			panic("no test case found, please provide one with -i and -a")
 		}
-		defer f.Close()
-		run.Stdin = f
+		cases = []testCase{{Input: input, Answer: answer}}
+	}
+	if len(cases) == 0 {
+		panic("no test case found, please provide one with -i and -a")
 	}
 
-	stop := spin("Running tests")
-	start := time.Now()
-	output, err := run.Output()
-	runTime := time.Since(start)
-	stop()
-	if err != nil {
-		if ee, ok := err.(*exec.ExitError); ok {
-			// Print the output generated before the crash.
-			if len(output) != 0 {
-				fmt.Printf("%s\n\n", output)
+	var maxTime time.Duration
+	for i, tc := range cases {
+		// name the failed test when there are several
+		label := ""
+		if len(cases) > 1 {
+			label = tc.Name
+			if label == "" {
+				label = fmt.Sprintf("Test %d", i+1)
 			}
-			if status, ok := ee.Sys().(syscall.WaitStatus); ok {
-				cprintf(red, bold, no+" Program exited with code %d\n\n", status.ExitStatus())
-			} else {
-				cprintf(red, bold, no+" Program exited with non-zero code\n\n")
+			label += ": "
+		}
+		stop := spin("Running tests")
+		r := runProgram(test, vars, "", tc.Input, prob.timeLimit())
+		stop()
+		if r.timedOut {
+			cprintf(red, bold, no+" %sTime limit exceeded (%s)\n", label, prob.TimeLimit)
+			cleanup()
+			os.Exit(1)
+		}
+		if r.exitCode != 0 {
+			// Print the output generated before the crash.
+			if r.output != "" {
+				fmt.Printf("%s\n\n", r.output)
 			}
-			fmt.Println(string(ee.Stderr))
+			cprintf(red, bold, no+" %sProgram exited with code %d\n\n", label, r.exitCode)
+			fmt.Println(r.stderr)
+			cleanup()
 			os.Exit(1)
-		} else {
-			panic(err)
 		}
-	}
-
-	if c.String("i") != "" {
-		if answerFile := c.String("a"); answerFile == "" {
-			// If the input is provided but there is no answer, we do not compare.
-			fmt.Println(string(output))
+		if !check {
+			fmt.Println(r.output)
 			return
-		} else {
-			data, err := ioutil.ReadFile(answerFile)
-			if err != nil {
-				panic(err)
-			}
-			answer = string(data)
 		}
-	}
 
-	sep := " "
-	if c.Bool("b") {
-		sep = ""
+		verdict, msg := compareOutput(prob, vars, tc.Input, r.output, tc.Answer, byteByByte)
+		if verdict != accepted {
+			cprintf(red, bold, no+" %s%s\n\n", label, verdict)
+			fmt.Print(msg)
+			return
+		}
+		if r.runTime > maxTime {
+			maxTime = r.runTime
+		}
 	}
-	diff, same := diff(answer, string(output), yes+" Answer", no+" Output", sep)
-	if same {
-		cprintf(cyan, bold, yes+" Accepted (%.3fs)\n", float32(runTime)/float32(time.Second))
+	if len(cases) > 1 {
+		cprintf(cyan, bold, yes+" Accepted %d tests (%.3fs)\n", len(cases), maxTime.Seconds())
 	} else {
-		cprintf(red, bold, no+" Wrong answer\n\n")
-		fmt.Print(diff)
+		cprintf(cyan, bold, yes+" Accepted (%.3fs)\n", maxTime.Seconds())
 	}
 }
 
@@ -307,6 +601,7 @@ func dump(c *cli.Context) {
 	}
 	file := c.Args().First()
 	pid, _, _ := parseFilename(file)
+	loadConfig()
 	input, answer := getTestData(pid)
 	if err := ioutil.WriteFile(c.String("i"), []byte(input), 0666); err != nil {
 		panic(err)
@@ -316,3 +611,38 @@ func dump(c *cli.Context) {
 	}
 	fmt.Printf("Dumped to %s and %s\n", colored(c.String("i"), yellow, underline), colored(c.String("a"), yellow, underline))
 }
+
+func samples(c *cli.Context) {
+	if c.NArg() == 0 {
+		panic("problem ID required")
+	}
+	pid, err := strconv.Atoi(c.Args().First())
+	if err != nil {
+		panic(err)
+	}
+	loadConfig()
+	input, output := getSamples(getProblemInfo(pid))
+	if input == "" && output == "" {
+		panic("no sample found in the problem description")
+	}
+	inputFile, answerFile := c.String("i"), c.String("a")
+	if inputFile == "" && answerFile == "" {
+		cprintf(white, bold, "Sample Input\n")
+		fmt.Println(input)
+		cprintf(white, bold, "Sample Output\n")
+		fmt.Print(output)
+		return
+	}
+	if inputFile != "" {
+		if err := ioutil.WriteFile(inputFile, []byte(input), 0666); err != nil {
+			panic(err)
+		}
+		fmt.Printf("Dumped input to %s\n", colored(inputFile, yellow, underline))
+	}
+	if answerFile != "" {
+		if err := ioutil.WriteFile(answerFile, []byte(output), 0666); err != nil {
+			panic(err)
+		}
+		fmt.Printf("Dumped answer to %s\n", colored(answerFile, yellow, underline))
+	}
+}
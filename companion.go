@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+// Competitive Companion is a browser extension that parses the problem page
+// you are on, and POSTs it to a local port when you click its button:
+//
+//	{"name": "A. Watermelon", "group": "Codeforces - Round 4", "url": "https://...",
+//	 "timeLimit": 1000, "memoryLimit": 64, "tests": [{"input": "8\n", "output": "YES\n"}]}
+//
+// Problems of UVa and the Live Archive keep their ID. Problems of other
+// judges get a local ID from receivedBaseID on.
+
+const companionPort = 27121
+
+type companionProblem struct {
+	Name  string
+	Group string
+	URL   string
+	// TimeLimit is in milliseconds.
+	TimeLimit int
+	Tests     []struct {
+		Input, Output string
+	}
+}
+
+// receivedProblem is a problem of another judge.
+type receivedProblem struct {
+	Info  problemInfo
+	URL   string
+	Group string
+}
+
+// Far above the IDs of UVa and the Live Archive.
+const receivedBaseID = 1000000
+
+var receivedFile = dataPath + "received.gob"
+
+func loadReceived() map[int]receivedProblem {
+	received := make(map[int]receivedProblem)
+	if exists(receivedFile) {
+		readGob(receivedFile, &received)
+	}
+	return received
+}
+
+// receivedJudge is where the problems received from other judges are.
+// Nothing can be submitted to them from here.
+type receivedJudge struct{}
+
+func (receivedJudge) name() string {
+	return "received"
+}
+
+func (receivedJudge) site() *url.URL {
+	return nil
+}
+
+func (receivedJudge) problems() map[int]problemInfo {
+	problems := make(map[int]problemInfo)
+	for pid, p := range loadReceived() {
+		problems[pid] = p.Info
+	}
+	return problems
+}
+
+func (receivedJudge) problemURL(info problemInfo) string {
+	return loadReceived()[info.ID].URL
+}
+
+func (j receivedJudge) statementURLs(info problemInfo) []string {
+	return []string{j.problemURL(info)}
+}
+
+func (receivedJudge) udebugURL(pid int) string {
+	panic("udebug.com only has the problems of UVa and the Live Archive")
+}
+
+func (receivedJudge) signIn(username, password string) []*http.Cookie {
+	panic("can not sign in to the judges of received problems")
+}
+
+func (receivedJudge) sessionValid() bool {
+	return true
+}
+
+func (j receivedJudge) submit(info problemInfo, file, ext string) string {
+	panic(fmt.Sprintf("%s was received from the browser, submit it on %s", file, j.problemURL(info)))
+}
+
+func (receivedJudge) result(sid string) (verdict, runTime string, err error) {
+	return "", "", notFoundError(fmt.Sprintf("submission %s is not on a known judge", sid))
+}
+
+// casesFile holds the tests of a received problem, which are run one by one.
+func casesFile(info problemInfo) string {
+	return testDataPath + info.getFileName("cases.gob")
+}
+
+// receivedCases returns the tests received for a problem of another judge.
+func receivedCases(pid int) (cases []testCase, ok bool) {
+	if pid < receivedBaseID {
+		return nil, false
+	}
+	file := casesFile(getProblemInfo(pid))
+	if !exists(file) {
+		return nil, false
+	}
+	readGob(file, &cases)
+	return cases, true
+}
+
+// findProblem returns the ID of the problem at u on UVa or the Live Archive.
+func (j *onlineJudge) findProblem(u *url.URL) (pid int, ok bool) {
+	if u.Host != j.site().Host {
+		return 0, false
+	}
+	trueID, err := strconv.Atoi(u.Query().Get("problem"))
+	if err != nil {
+		return 0, false
+	}
+	for _, info := range j.problems() {
+		if info.TrueID == trueID {
+			return info.ID, true
+		}
+	}
+	return 0, false
+}
+
+// receive saves a problem from Competitive Companion, and returns its ID.
+func receive(p companionProblem) int {
+	u, err := url.Parse(p.URL)
+	if err != nil {
+		panic(err)
+	}
+	var tests []testCase
+	for _, t := range p.Tests {
+		tests = append(tests, testCase{Input: t.Input, Answer: t.Output})
+	}
+
+	for _, j := range []*onlineJudge{uvaJudge, liveArchive} {
+		pid, ok := j.findProblem(u)
+		if !ok {
+			continue
+		}
+		if judgeFor(pid).name() != j.name() {
+			panic(fmt.Sprintf("problem %d is set to judge %s in config", pid, judgeFor(pid).name()))
+		}
+		// These judges run all cases in one input, so the tests are
+		// the sample of `uva test -s`.
+		var input, output strings.Builder
+		for _, t := range tests {
+			input.WriteString(t.Input)
+			output.WriteString(t.Answer)
+		}
+		writeGob(testDataPath+getProblemInfo(pid).getFileName("sample.gob"), input.String(), output.String())
+		return pid
+	}
+
+	received := loadReceived()
+	pid := receivedBaseID
+	for id, r := range received {
+		if r.URL == p.URL {
+			pid = id
+			break
+		}
+		if id >= pid {
+			pid = id + 1
+		}
+	}
+	received[pid] = receivedProblem{
+		Info: problemInfo{
+			Title:     p.Name,
+			ID:        pid,
+			TimeLimit: time.Duration(p.TimeLimit) * time.Millisecond,
+		},
+		URL:   p.URL,
+		Group: p.Group,
+	}
+	writeGob(receivedFile, received)
+	writeGob(casesFile(received[pid].Info), tests)
+	return pid
+}
+
+// extensionOrigin reports whether origin is a browser extension, like
+// Competitive Companion, and not a web page.
+func extensionOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && (u.Scheme == "chrome-extension" || u.Scheme == "moz-extension")
+}
+
+func listen(c *cli.Context) {
+	loadConfig()
+	lang := defaultLang(c.String("lang"))
+	// requests are handled one at a time, as a contest comes as a batch
+	var mu sync.Mutex
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "use POST to send a problem", http.StatusMethodNotAllowed)
+			return
+		}
+		// Web pages can POST here too. A JSON body needs a CORS preflight,
+		// which is never answered, and pages always send their Origin.
+		if ct := r.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+			http.Error(w, "the problem must be sent as application/json", http.StatusUnsupportedMediaType)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" && !extensionOrigin(origin) {
+			http.Error(w, "requests from web pages are not accepted", http.StatusForbidden)
+			return
+		}
+		var p companionProblem
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		defer func() {
+			if err := recover(); err != nil {
+				cprintf(red, bold, "%s %s: %s\n", no, p.Name, err)
+				http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
+			}
+		}()
+		pid := receive(p)
+		info := getProblemInfo(pid)
+		fmt.Printf("Received %s with %d tests\n", colored(fmt.Sprintf("%d - %s", pid, info.Title), white, bold), len(p.Tests))
+		if name := info.getFileName(lang); exists(name) {
+			fmt.Printf("Continue with %s\n", colored(name, yellow, underline))
+		} else {
+			touchProblem(pid, lang, false, false)
+		}
+	}
+	addr := fmt.Sprintf("127.0.0.1:%d", c.Int("port"))
+	fmt.Printf("Listening on %s, click the Competitive Companion button on a problem page\n", colored(addr, yellow, underline))
+	panic(http.ListenAndServe(addr, http.HandlerFunc(handler)))
+}
@@ -0,0 +1,524 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Configuration is merged from these files, later ones taking precedence:
+//
+//  1. config.yml in the data directory
+//  2. .uva.yml in the current directory or the nearest parent, usually
+//     committed next to the solutions and shared by a team
+//
+// Settings of a single problem go under "problems" in either file.
+const projectConfigName = ".uva.yml"
+
+// defaultConfig is written to the data directory on first use.
+//
+//go:embed config.yml
+var defaultConfig []byte
+
+var globalConfigFile = dataPath + "config.yml"
+
+type testConfig struct {
+	Compile, Run []string
+}
+
+// problemConfig holds the settings that can be overridden per problem.
+type problemConfig struct {
+	TimeLimit string `yaml:"time_limit"`
+	// Checker is run instead of the diff, and accepts the output when it
+	// exits with 0. It can use {input}, {output} and {answer} for the paths
+	// of the test files.
+	Checker []string
+	// Tolerance is the absolute or relative error allowed between floating
+	// point numbers in the output and the answer.
+	Tolerance float64
+	// Judge is where problems are submitted: uva, live-archive or local.
+	Judge string
+	// Solution is the reference solution exported with `uva export`,
+	// relative to the config file. It is only allowed per problem.
+	Solution string
+	// validator tells that Checker is the output validator of a problem
+	// package, which reads the output from stdin, and exits with 42 if it
+	// is correct or 43 if not. It can use {feedback} for its directory.
+	validator bool
+	// ignoreCase and ignoreSpace make the diff accept outputs that differ
+	// in case or spacing only, like the default validator of Kattis.
+	ignoreCase, ignoreSpace bool
+}
+
+type configFile struct {
+	problemConfig `yaml:",inline"`
+	Test          map[string]testConfig
+	Lang          string
+	// Author is used in source templates, and defaults to the username.
+	Author string
+	// PdfReader is auto, pdftotext or native. auto uses pdftotext if it
+	// is installed.
+	PdfReader string `yaml:"pdf_reader"`
+	// Viewer is the command to open PDFs with, `uva show -g` appends the
+	// file to it.
+	Viewer []string
+	// Credentials is where remembered passwords are saved: auto, keyring,
+	// file or none. auto uses the keyring if the Secret Service is running.
+	Credentials string
+	// Source of the problem list: uhunt or html. uhunt falls back to
+	// scraping the judge's web pages when it fails.
+	Source string
+	// JudgeURL is the base URL of a `uva judge-server` to submit to,
+	// instead of onlinejudge.org.
+	JudgeURL string `yaml:"judge_url"`
+	// ResultTimeout is how long `uva submit` waits for the verdict.
+	ResultTimeout string `yaml:"result_timeout"`
+	Problems      map[int]problemConfig
+}
+
+var (
+	config configFile
+	// configSources maps every key set in config, e.g. "test.cpp.run",
+	// to the file it came from.
+	configSources map[string]string
+)
+
+func loadConfig() {
+	if !exists(globalConfigFile) {
+		writeDefaultConfig()
+	}
+	files := []string{globalConfigFile}
+	if projectFile := findProjectConfig(); projectFile != "" {
+		files = append(files, projectFile)
+	}
+
+	config = configFile{}
+	configSources = make(map[string]string)
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			panic(err)
+		}
+		var layer configFile
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		// report unknown keys instead of ignoring them
+		dec.SetStrict(true)
+		if err := dec.Decode(&layer); err != nil && err != io.EOF {
+			panic(fmt.Errorf("%s: %s", file, err))
+		}
+		if err := checkLayer(layer); err != nil {
+			panic(fmt.Errorf("%s: %s", file, err))
+		}
+		config.merge(layer, layerKeys(data), file)
+	}
+	if err := checkConfig(); err != nil {
+		panic(err)
+	}
+}
+
+func writeDefaultConfig() {
+	if err := ioutil.WriteFile(globalConfigFile, defaultConfig, 0644); err != nil {
+		panic(err)
+	}
+}
+
+// checkLayer reports entries that decode fine but make no sense, before they
+// are merged and lose the file they came from.
+func checkLayer(layer configFile) error {
+	switch layer.PdfReader {
+	case "", "auto", "pdftotext", "native":
+	default:
+		return fmt.Errorf("pdf_reader: should be auto, pdftotext or native, not %q", layer.PdfReader)
+	}
+	switch layer.Source {
+	case "", "uhunt", "html":
+	default:
+		return fmt.Errorf("source: should be uhunt or html, not %q", layer.Source)
+	}
+	if layer.JudgeURL != "" {
+		if u, err := url.Parse(layer.JudgeURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("judge_url: should be a URL like http://localhost:8080, not %q", layer.JudgeURL)
+		}
+	}
+	switch layer.Credentials {
+	case "", "auto", "keyring", "file", "none":
+	default:
+		return fmt.Errorf("credentials: should be auto, keyring, file or none, not %q", layer.Credentials)
+	}
+	for ext, t := range layer.Test {
+		if ext == "" || strings.ContainsAny(ext, "./ ") {
+			return fmt.Errorf("test.%s: the key should be a file extension like cpp", ext)
+		}
+		if t.Compile == nil && t.Run == nil {
+			return fmt.Errorf("test.%s: compile or run command is required", ext)
+		}
+	}
+	if layer.Solution != "" {
+		return fmt.Errorf("solution: only allowed per problem, under problems")
+	}
+	for pid := range layer.Problems {
+		if pid <= 0 {
+			return fmt.Errorf("problems.%d: the key should be a problem ID", pid)
+		}
+	}
+	return nil
+}
+
+func findProjectConfig() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		panic(err)
+	}
+	for {
+		file := filepath.Join(dir, projectConfigName)
+		if exists(file) {
+			return file
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// layerKeys returns the keys set in a config file, e.g. "test.cpp.run", so
+// that zero values like tolerance: 0 override the layers below.
+func layerKeys(data []byte) map[string]bool {
+	keys := make(map[string]bool)
+	var walk func(prefix string, v interface{})
+	walk = func(prefix string, v interface{}) {
+		m, ok := v.(map[interface{}]interface{})
+		if !ok {
+			return
+		}
+		for k, v := range m {
+			key := prefix + fmt.Sprint(k)
+			keys[key] = true
+			walk(key+".", v)
+		}
+	}
+	var root interface{}
+	// errors were reported by the strict decoding
+	yaml.Unmarshal(data, &root)
+	walk("", root)
+	return keys
+}
+
+func (p *problemConfig) merge(layer problemConfig, keys map[string]bool, prefix, source string) {
+	if keys[prefix+"time_limit"] {
+		p.TimeLimit = layer.TimeLimit
+		configSources[prefix+"time_limit"] = source
+	}
+	if keys[prefix+"checker"] {
+		p.Checker = layer.Checker
+		configSources[prefix+"checker"] = source
+	}
+	if keys[prefix+"tolerance"] {
+		p.Tolerance = layer.Tolerance
+		configSources[prefix+"tolerance"] = source
+	}
+	if keys[prefix+"judge"] {
+		p.Judge = layer.Judge
+		configSources[prefix+"judge"] = source
+	}
+	if layer.Solution != "" {
+		p.Solution = layer.Solution
+		if !filepath.IsAbs(p.Solution) {
+			p.Solution = filepath.Join(filepath.Dir(source), p.Solution)
+		}
+		configSources[prefix+"solution"] = source
+	}
+}
+
+func (c *configFile) merge(layer configFile, keys map[string]bool, source string) {
+	c.problemConfig.merge(layer.problemConfig, keys, "", source)
+	if keys["lang"] {
+		c.Lang = layer.Lang
+		configSources["lang"] = source
+	}
+	if keys["author"] {
+		c.Author = layer.Author
+		configSources["author"] = source
+	}
+	if keys["pdf_reader"] {
+		c.PdfReader = layer.PdfReader
+		configSources["pdf_reader"] = source
+	}
+	if keys["viewer"] {
+		c.Viewer = layer.Viewer
+		configSources["viewer"] = source
+	}
+	if keys["credentials"] {
+		c.Credentials = layer.Credentials
+		configSources["credentials"] = source
+	}
+	if keys["source"] {
+		c.Source = layer.Source
+		configSources["source"] = source
+	}
+	if keys["judge_url"] {
+		c.JudgeURL = layer.JudgeURL
+		configSources["judge_url"] = source
+	}
+	if keys["result_timeout"] {
+		c.ResultTimeout = layer.ResultTimeout
+		configSources["result_timeout"] = source
+	}
+	for ext, t := range layer.Test {
+		if c.Test == nil {
+			c.Test = make(map[string]testConfig)
+		}
+		cur := c.Test[ext]
+		if keys["test."+ext+".compile"] {
+			cur.Compile = t.Compile
+			configSources["test."+ext+".compile"] = source
+		}
+		if keys["test."+ext+".run"] {
+			cur.Run = t.Run
+			configSources["test."+ext+".run"] = source
+		}
+		c.Test[ext] = cur
+	}
+	for pid, p := range layer.Problems {
+		if c.Problems == nil {
+			c.Problems = make(map[int]problemConfig)
+		}
+		cur := c.Problems[pid]
+		cur.merge(p, keys, fmt.Sprintf("problems.%d.", pid), source)
+		c.Problems[pid] = cur
+	}
+}
+
+// problem returns the settings of a problem, with the per-problem overrides
+// applied to the top-level ones.
+func (c configFile) problem(pid int) problemConfig {
+	p := c.problemConfig
+	override := c.Problems[pid]
+	set := func(key string) bool {
+		_, ok := configSources[fmt.Sprintf("problems.%d.%s", pid, key)]
+		return ok
+	}
+	if set("time_limit") {
+		p.TimeLimit = override.TimeLimit
+	}
+	if set("checker") {
+		p.Checker = override.Checker
+	}
+	if set("tolerance") {
+		p.Tolerance = override.Tolerance
+	}
+	if set("judge") {
+		p.Judge = override.Judge
+	}
+	p.Solution = override.Solution
+	return p
+}
+
+// timeLimit returns 0 if there is no limit. The value is validated by checkConfig.
+func (p problemConfig) timeLimit() time.Duration {
+	d, _ := time.ParseDuration(p.TimeLimit)
+	return d
+}
+
+func sourceOf(key string) string {
+	if source, ok := configSources[key]; ok {
+		return source
+	}
+	return "config"
+}
+
+// checkConfig reports the first malformed setting.
+func checkConfig() error {
+	vars := newCmdVars("main.c", 0)
+	checkCmd := func(key string, cmd []string, vars cmdVars) error {
+		for i, arg := range cmd {
+			if _, err := vars.expand(arg); err != nil {
+				return fmt.Errorf("%s: %s[%d]: %s", sourceOf(key), key, i, err)
+			}
+		}
+		return nil
+	}
+	checkProblem := func(prefix string, p problemConfig) error {
+		if p.TimeLimit != "" {
+			if d, err := time.ParseDuration(p.TimeLimit); err != nil || d < 0 {
+				return fmt.Errorf("%s: %stime_limit: invalid duration %q, use a value like 3s or 500ms",
+					sourceOf(prefix+"time_limit"), prefix, p.TimeLimit)
+			}
+		}
+		if p.Tolerance < 0 {
+			return fmt.Errorf("%s: %stolerance: must not be negative", sourceOf(prefix+"tolerance"), prefix)
+		}
+		if p.Judge != "" {
+			if !knownJudge(p.Judge) {
+				return fmt.Errorf("%s: %sjudge: should be one of %s, not %q",
+					sourceOf(prefix+"judge"), prefix, strings.Join(judgeNames(), ", "), p.Judge)
+			}
+			if p.Judge == localJudgeName && config.JudgeURL == "" {
+				return fmt.Errorf("%s: %sjudge: local requires judge_url", sourceOf(prefix+"judge"), prefix)
+			}
+		}
+		return checkCmd(prefix+"checker", p.Checker, vars.with(checkerVars("", "", "")))
+	}
+
+	if t := config.ResultTimeout; t != "" {
+		if d, err := time.ParseDuration(t); err != nil || d <= 0 {
+			return fmt.Errorf("%s: result_timeout: invalid duration %q, use a value like 90s or 5m",
+				sourceOf("result_timeout"), t)
+		}
+	}
+	if err := checkProblem("", config.problemConfig); err != nil {
+		return err
+	}
+	exts := make([]string, 0, len(config.Test))
+	for ext := range config.Test {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	for _, ext := range exts {
+		t := config.Test[ext]
+		key := "test." + ext
+		if len(t.Run) == 0 {
+			return fmt.Errorf("%s: %s: run command is required", sourceOf(key+".compile"), key)
+		}
+		if err := checkCmd(key+".compile", t.Compile, vars); err != nil {
+			return err
+		}
+		if err := checkCmd(key+".run", t.Run, vars); err != nil {
+			return err
+		}
+	}
+	for _, pid := range sortedProblemIDs(config.Problems) {
+		if err := checkProblem(fmt.Sprintf("problems.%d.", pid), config.Problems[pid]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkerVars(input, output, answer string) cmdVars {
+	return cmdVars{"input": input, "output": output, "answer": answer}
+}
+
+func sortedProblemIDs(problems map[int]problemConfig) []int {
+	ids := make([]int, 0, len(problems))
+	for pid := range problems {
+		ids = append(ids, pid)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// flowList formats a command like [g++, -O2, '{file}'].
+func flowList(cmd []string) string {
+	items := make([]string, len(cmd))
+	for i, s := range cmd {
+		out, err := yaml.Marshal(s)
+		if err != nil {
+			panic(err)
+		}
+		items[i] = strings.TrimSpace(string(out))
+	}
+	return "[" + strings.Join(items, ", ") + "]"
+}
+
+func initConfig(c *cli.Context) {
+	writeNewFile(globalConfigFile, defaultConfig, c.Bool("force"))
+	fmt.Printf("Created %s\n", colored(globalConfigFile, yellow, underline))
+}
+
+func showConfig(c *cli.Context) {
+	loadConfig()
+	home := os.Getenv("HOME")
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	line := func(indent int, key, value, sourceKey string) {
+		fmt.Fprintf(w, "%s%s: %s", strings.Repeat("  ", indent), key, value)
+		if source, ok := configSources[sourceKey]; ok {
+			if home != "" && strings.HasPrefix(source, home+"/") {
+				source = "~" + source[len(home):]
+			}
+			fmt.Fprintf(w, "\t# %s", source)
+		}
+		fmt.Fprintln(w)
+	}
+	problem := func(indent int, prefix string, p problemConfig) {
+		if p.TimeLimit != "" {
+			line(indent, "time_limit", p.TimeLimit, prefix+"time_limit")
+		}
+		if p.Checker != nil {
+			line(indent, "checker", flowList(p.Checker), prefix+"checker")
+		}
+		if _, set := configSources[prefix+"tolerance"]; set || p.Tolerance != 0 {
+			line(indent, "tolerance", strconv.FormatFloat(p.Tolerance, 'g', -1, 64), prefix+"tolerance")
+		}
+		if p.Judge != "" {
+			line(indent, "judge", p.Judge, prefix+"judge")
+		}
+		if p.Solution != "" {
+			line(indent, "solution", p.Solution, prefix+"solution")
+		}
+	}
+
+	if config.Lang != "" {
+		line(0, "lang", config.Lang, "lang")
+	}
+	if config.Author != "" {
+		line(0, "author", config.Author, "author")
+	}
+	if config.PdfReader != "" {
+		line(0, "pdf_reader", config.PdfReader, "pdf_reader")
+	}
+	if config.Viewer != nil {
+		line(0, "viewer", flowList(config.Viewer), "viewer")
+	}
+	if config.Credentials != "" {
+		line(0, "credentials", config.Credentials, "credentials")
+	}
+	if config.Source != "" {
+		line(0, "source", config.Source, "source")
+	}
+	if config.JudgeURL != "" {
+		line(0, "judge_url", config.JudgeURL, "judge_url")
+	}
+	if config.ResultTimeout != "" {
+		line(0, "result_timeout", config.ResultTimeout, "result_timeout")
+	}
+	problem(0, "", config.problemConfig)
+	if len(config.Test) != 0 {
+		fmt.Fprintln(w, "test:")
+		exts := make([]string, 0, len(config.Test))
+		for ext := range config.Test {
+			exts = append(exts, ext)
+		}
+		sort.Strings(exts)
+		for _, ext := range exts {
+			t := config.Test[ext]
+			fmt.Fprintf(w, "  %s:\n", ext)
+			if t.Compile != nil {
+				line(2, "compile", flowList(t.Compile), "test."+ext+".compile")
+			}
+			line(2, "run", flowList(t.Run), "test."+ext+".run")
+		}
+	}
+	if len(config.Problems) != 0 {
+		fmt.Fprintln(w, "problems:")
+		for _, pid := range sortedProblemIDs(config.Problems) {
+			fmt.Fprintf(w, "  %d:\n", pid)
+			problem(2, fmt.Sprintf("problems.%d.", pid), config.Problems[pid])
+		}
+	}
+	w.Flush()
+}
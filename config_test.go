@@ -0,0 +1,89 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testGlobalConfig = `
+lang: cpp
+time_limit: 3s
+tolerance: 1e-6
+test:
+  cpp:
+    compile: [g++, '{file}', -o, '{out}']
+    run: ['{out}']
+problems:
+  100:
+    tolerance: 1e-3
+`
+
+const testProjectConfig = `
+lang: py
+test:
+  cpp:
+    run: [valgrind, '{out}']
+problems:
+  100:
+    tolerance: 0
+  200:
+    time_limit: 1s
+`
+
+func TestLoadConfigLayers(t *testing.T) {
+	dir := t.TempDir()
+	saved := globalConfigFile
+	globalConfigFile = filepath.Join(dir, "config.yml")
+	defer func() { globalConfigFile = saved }()
+	project := filepath.Join(dir, "work")
+	if err := os.Mkdir(project, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(globalConfigFile, []byte(testGlobalConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	projectFile := filepath.Join(project, projectConfigName)
+	if err := ioutil.WriteFile(projectFile, []byte(testProjectConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir(project); err != nil {
+		t.Fatal(err)
+	}
+
+	loadConfig()
+	if config.Lang != "py" || sourceOf("lang") != projectFile {
+		t.Errorf("lang = %q from %s, want py from %s", config.Lang, sourceOf("lang"), projectFile)
+	}
+	cpp := config.Test["cpp"]
+	if want := []string{"g++", "{file}", "-o", "{out}"}; !reflect.DeepEqual(cpp.Compile, want) {
+		t.Errorf("test.cpp.compile = %q, want %q", cpp.Compile, want)
+	}
+	if want := []string{"valgrind", "{out}"}; !reflect.DeepEqual(cpp.Run, want) {
+		t.Errorf("test.cpp.run = %q, want %q", cpp.Run, want)
+	}
+
+	tests := []struct {
+		pid       int
+		timeLimit string
+		tolerance float64
+	}{
+		{100, "3s", 0},
+		{200, "1s", 1e-6},
+		{300, "3s", 1e-6},
+	}
+	for _, test := range tests {
+		p := config.problem(test.pid)
+		if p.TimeLimit != test.timeLimit || p.Tolerance != test.tolerance {
+			t.Errorf("problem %d: time_limit %q, tolerance %g, want %q, %g",
+				test.pid, p.TimeLimit, p.Tolerance, test.timeLimit, test.tolerance)
+		}
+	}
+}
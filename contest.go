@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+// A virtual contest is a set of problems to solve before a deadline. The
+// verdicts come from the local history, so everything submitted with
+// `uva submit` during the contest counts, and it survives across terminal
+// sessions.
+
+var contestFile = dataPath + "contest.gob"
+
+type contestInfo struct {
+	Problems []int
+	Start    time.Time
+	Duration time.Duration
+}
+
+// Wrong submissions before the accepted one add 20 minutes to the penalty.
+const penaltyPerAttempt = 20 * time.Minute
+
+func (c contestInfo) end() time.Time {
+	return c.Start.Add(c.Duration)
+}
+
+func (c contestInfo) running() bool {
+	return time.Now().Before(c.end())
+}
+
+func (c contestInfo) has(pid int) bool {
+	return containsInt(c.Problems, pid)
+}
+
+func loadContest() (contest contestInfo, ok bool) {
+	if !exists(contestFile) {
+		return contest, false
+	}
+	readGob(contestFile, &contest)
+	return contest, true
+}
+
+// problemLabel names problems A, B, C... like in ICPC contests.
+func problemLabel(i int) string {
+	if i < 26 {
+		return string(rune('A' + i))
+	}
+	return strconv.Itoa(i + 1)
+}
+
+type problemResult struct {
+	solved bool
+	// rejected submissions, before the accepted one if it is solved
+	attempts int
+	// time of the accepted submission from the start
+	solvedAt time.Duration
+	pending  int
+}
+
+// penalty is the ICPC penalty of a solved problem.
+func (r problemResult) penalty() time.Duration {
+	if !r.solved {
+		return 0
+	}
+	return r.solvedAt.Truncate(time.Minute) + time.Duration(r.attempts)*penaltyPerAttempt
+}
+
+// countsForPenalty reports whether a rejected verdict counts as an attempt.
+// Compilation errors and the judge's own failures don't.
+func countsForPenalty(verdict string) bool {
+	v := strings.ToLower(verdict)
+	return !strings.HasPrefix(v, "compil") && v != "submission error" && v != "can't be judged"
+}
+
+func (c contestInfo) results() map[int]*problemResult {
+	results := make(map[int]*problemResult)
+	for _, pid := range c.Problems {
+		results[pid] = &problemResult{}
+	}
+	var subs []submission
+	for _, s := range loadHistory() {
+		if c.has(s.Problem) && !s.Time.Before(c.Start) && s.Time.Before(c.end()) {
+			subs = append(subs, s)
+		}
+	}
+	sortSubmissions(subs)
+	for _, s := range subs {
+		r := results[s.Problem]
+		switch {
+		case r.solved:
+		case s.Verdict == "" || pendingResults[s.Verdict]:
+			r.pending++
+		case s.Verdict == "Accepted":
+			r.solved = true
+			r.solvedAt = s.Time.Sub(c.Start)
+		case countsForPenalty(s.Verdict):
+			r.attempts++
+		}
+	}
+	return results
+}
+
+func formatDuration(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	d = d.Truncate(time.Second)
+	return fmt.Sprintf("%d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
+
+// scoreboard formats the results like an ICPC scoreboard.
+func (c contestInfo) scoreboard() string {
+	var buf strings.Builder
+	if c.running() {
+		fmt.Fprintf(&buf, "%s %s left\n\n", colored("Contest running,", white, bold), formatDuration(time.Until(c.end())))
+	} else {
+		fmt.Fprintf(&buf, "%s\n\n", colored("Contest over", white, bold))
+	}
+	problems := loadProblems()
+	results := c.results()
+	w := tabwriter.NewWriter(&buf, 0, 8, 2, ' ', 0)
+	solved := 0
+	var penalty time.Duration
+	for i, pid := range c.Problems {
+		r := results[pid]
+		var status string
+		switch {
+		case r.solved:
+			solved++
+			penalty += r.penalty()
+			status = colored(fmt.Sprintf("%s %s", yes, formatDuration(r.solvedAt)), cyan, bold)
+			if r.attempts != 0 {
+				status += fmt.Sprintf(" (+%d)", r.attempts)
+			}
+		case r.attempts != 0:
+			status = colored(fmt.Sprintf("%s -%d", no, r.attempts), red, bold)
+		default:
+			status = "-"
+		}
+		if r.pending != 0 {
+			status += " (judging)"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", colored(problemLabel(i), yellow, bold), pid, problems[pid].Title, status)
+	}
+	w.Flush()
+	fmt.Fprintf(&buf, "\nSolved: %d/%d, penalty: %d\n", solved, len(c.Problems), int(penalty.Minutes()))
+	return buf.String()
+}
+
+// contestNotice prints the standing after a submission to a running contest.
+func contestNotice(pid int) {
+	contest, ok := loadContest()
+	if !ok || !contest.has(pid) {
+		return
+	}
+	if !contest.running() {
+		cprintf(magenta, 0, "The contest is over, this submission does not count\n")
+		return
+	}
+	fmt.Print("\n" + contest.scoreboard())
+}
+
+func contestStart(c *cli.Context) {
+	loadConfig()
+	if contest, ok := loadContest(); ok && contest.running() && !c.Bool("force") {
+		panic("a contest is running, stop it with `uva contest stop` or use --force")
+	}
+	duration, err := time.ParseDuration(c.String("duration"))
+	if err != nil || duration <= 0 {
+		panic(fmt.Sprintf("invalid duration %q, use a value like 5h or 90m", c.String("duration")))
+	}
+	var contest contestInfo
+	for _, s := range strings.Split(c.String("problems"), ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		pid := parsePid(s)
+		getProblemInfo(pid)
+		if !contest.has(pid) {
+			contest.Problems = append(contest.Problems, pid)
+		}
+	}
+	if len(contest.Problems) == 0 {
+		panic("problems required, e.g. --problems 100,101,10041")
+	}
+	contest.Start = time.Now()
+	contest.Duration = duration
+	writeGob(contestFile, contest)
+	fmt.Print(contest.scoreboard())
+}
+
+func contestStatus(c *cli.Context) {
+	loadConfig()
+	contest, ok := loadContest()
+	if !ok {
+		panic("no contest, start one with `uva contest start`")
+	}
+	if !c.Bool("watch") || !isTerminal {
+		fmt.Print(contest.scoreboard())
+		return
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	for {
+		// clear the screen
+		fmt.Print("\033[H\033[2J" + contest.scoreboard())
+		if !contest.running() {
+			return
+		}
+		select {
+		case <-interrupt:
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func contestStop(c *cli.Context) {
+	loadConfig()
+	contest, ok := loadContest()
+	if !ok {
+		panic("no contest to stop")
+	}
+	if contest.running() {
+		// end it now, so the scoreboard is final
+		contest.Duration = time.Since(contest.Start)
+	}
+	fmt.Print(contest.scoreboard())
+	if err := os.Remove(contestFile); err != nil {
+		panic(err)
+	}
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"encoding/gob"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -13,15 +12,65 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
-	"golang.org/x/crypto/ssh/terminal"
 	"golang.org/x/net/publicsuffix"
 )
 
-const baseURL = "https://onlinejudge.org"
+// onlineJudge scrapes a judge running the Joomla onlinejudge component,
+// like UVa and the ICPC Live Archive.
+type onlineJudge struct {
+	key, title string
+	baseURL    string
+	// categories list the problem volumes
+	categories   []int
+	problemsFile string
+	// udebug is the category of the judge on udebug.com
+	udebug string
+	// uhunt tells whether uHunt knows the problems
+	uhunt bool
+}
+
+func (j *onlineJudge) name() string {
+	return j.key
+}
+
+func (j *onlineJudge) site() *url.URL {
+	u, err := url.Parse(j.baseURL)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
 
-var uvaURL, _ = url.Parse(baseURL)
+func (j *onlineJudge) problemURL(info problemInfo) string {
+	return fmt.Sprintf("%s/index.php?option=com_onlinejudge&Itemid=8&page=show_problem&problem=%d", j.baseURL, info.TrueID)
+}
+
+func (j *onlineJudge) statementURLs(info problemInfo) []string {
+	return []string{
+		fmt.Sprintf("%s/external/%d/p%d.pdf", j.baseURL, info.ID/100, info.ID),
+		fmt.Sprintf("%s/external/%d/%d.html", j.baseURL, info.ID/100, info.ID),
+	}
+}
+
+func (j *onlineJudge) udebugURL(pid int) string {
+	return fmt.Sprintf("https://www.udebug.com/%s/%d", j.udebug, pid)
+}
+
+// problems returns all problems of the judge, downloading the list on first
+// use from the source in config, which the commands have loaded.
+func (j *onlineJudge) problems() map[int]problemInfo {
+	var problems map[int]problemInfo
+	if exists(j.problemsFile) {
+		readGob(j.problemsFile, &problems)
+	} else {
+		problems = j.fetchProblems(config.Source)
+		writeGob(j.problemsFile, problems)
+	}
+	return problems
+}
 
 type problemInfo struct {
 	Title            string
@@ -29,9 +78,25 @@ type problemInfo struct {
 	TrueID           int
 	TotalSubmissions int
 	Percentage       float32
+	// These are only known when the problem list comes from uHunt.
+	TimeLimit   time.Duration
+	BestRuntime time.Duration
+	// DACU is the number of distinct users who solved the problem.
+	DACU   int
+	Status int
+}
+
+func (info problemInfo) url() string {
+	return judgeFor(info.ID).problemURL(info)
 }
 
-func crawlProblemsInfo() map[int]problemInfo {
+// available reports whether the problem accepts submissions. Only uHunt
+// knows it, and it knows the time limits too.
+func (info problemInfo) available() bool {
+	return info.Status != problemUnavailable || info.TimeLimit == 0
+}
+
+func (j *onlineJudge) crawlProblems() map[int]problemInfo {
 	// First, get all volumes' URL from two categories - "Problem Set Volumes" and "Contest Volumes".
 	volumesChan := make(chan string)
 	var volumesWaitGroup sync.WaitGroup
@@ -43,7 +108,7 @@ func crawlProblemsInfo() map[int]problemInfo {
 			}
 		}()
 
-		resp, err := http.Get(fmt.Sprintf("%s/index.php?option=com_onlinejudge&Itemid=8&category=%d", baseURL, category))
+		resp, err := http.Get(fmt.Sprintf("%s/index.php?option=com_onlinejudge&Itemid=8&category=%d", j.baseURL, category))
 		if err != nil {
 			panic(err)
 		}
@@ -86,7 +151,7 @@ func crawlProblemsInfo() map[int]problemInfo {
 		}()
 
 		for volumeURL := range volumesChan {
-			resp, err := http.Get(fmt.Sprintf("%s/%s", baseURL, volumeURL))
+			resp, err := http.Get(fmt.Sprintf("%s/%s", j.baseURL, volumeURL))
 			if err != nil {
 				panic(err)
 			}
@@ -136,7 +201,7 @@ func crawlProblemsInfo() map[int]problemInfo {
 
 func crawlTestData(pid int) (input string, output string) {
 	defer spin("Downloading test cases")()
-	problemHomePage := fmt.Sprintf("https://www.udebug.com/UVa/%d", pid)
+	problemHomePage := judgeFor(pid).udebugURL(pid)
 	doc, err := goquery.NewDocument(problemHomePage)
 	if err != nil {
 		panic(err)
@@ -189,26 +254,100 @@ type loginInfo struct {
 	// Export these fields so that gob can dump them.
 	Username string
 	Cookies  []*http.Cookie
+	// Judge is the judge the profile logs in to, empty for UVa.
+	Judge string
 }
 
-func login() (username string) {
-	fmt.Print("Username: ")
-	fmt.Scanln(&username)
-	fmt.Print("Password: ")
-	password, err := terminal.ReadPassword(0)
-	fmt.Print("\n")
-	if err != nil {
-		panic(err)
+func (info loginInfo) judge() string {
+	if info.Judge == "" {
+		return defaultJudge
 	}
+	return info.Judge
+}
+
+// profileFor returns the profile to sign in to a judge: the current one if
+// it was chosen with --profile or is for the judge, or else the first
+// profile for the judge.
+func profileFor(j judge) string {
+	profile := currentProfile()
+	if selectedProfile != "" || profileJudge(profile) == j.name() {
+		return profile
+	}
+	for _, name := range listProfiles() {
+		if profileJudge(name) == j.name() {
+			return name
+		}
+	}
+	if j.name() == defaultJudge {
+		// not logged in, loadProfile tells so
+		return profile
+	}
+	panic(fmt.Sprintf("no profile for %s, create one with `uva user -l --judge %s --profile NAME`", j.name(), j.name()))
+}
 
+// profileJudge returns the judge of a profile, or "" if it does not exist.
+func profileJudge(profile string) string {
+	if !exists(loginInfoFile(profile)) {
+		return ""
+	}
+	var info loginInfo
+	readGob(loginInfoFile(profile), &info)
+	return info.judge()
+}
+
+const sessionExpired = "session expired, please run `uva user -l` to sign in again"
+
+// login signs in and saves the session to a profile. The username and
+// password default to $UVA_USERNAME and $UVA_PASSWORD, and are prompted for
+// otherwise. With passwordStdin the password is the first line of stdin.
+func login(j judge, profile, username string, passwordStdin, remember bool) string {
+	if username == "" {
+		username = os.Getenv("UVA_USERNAME")
+	}
+	if username == "" {
+		if passwordStdin {
+			panic("--password-stdin requires --username or $UVA_USERNAME")
+		}
+		username = strings.TrimSpace(readLine("Username: "))
+	}
+	if username == "" {
+		panic("username required")
+	}
+	var password string
+	switch {
+	case passwordStdin:
+		password = readLine("")
+	case os.Getenv("UVA_PASSWORD") != "":
+		password = os.Getenv("UVA_PASSWORD")
+	default:
+		password = readPassword("Password: ")
+	}
+
+	info := loginInfo{
+		Username: username,
+		Cookies:  j.signIn(username, password),
+	}
+	if j.name() != defaultJudge {
+		info.Judge = j.name()
+	}
+	saveLoginInfo(profile, info)
+	if remember {
+		rememberPassword(profile, username, password)
+	}
+	return username
+}
+
+// signIn logs in to the judge with a new cookie jar, and returns the
+// session cookies.
+func (j *onlineJudge) signIn(username, password string) []*http.Cookie {
 	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
 	if err != nil {
 		panic(err)
 	}
 	http.DefaultClient.Jar = jar
 
-	defer spin("Signing in onlinejudge.org (UVa)")()
-	resp, err := http.Get(baseURL)
+	defer spin("Signing in " + j.title)()
+	resp, err := http.Get(j.baseURL)
 	if err != nil {
 		panic(err)
 	}
@@ -224,9 +363,9 @@ func login() (username string) {
 			form.Set(name, value)
 		})
 	form.Set("username", username)
-	form.Set("passwd", string(password))
+	form.Set("passwd", password)
 	r, err := http.PostForm(
-		baseURL+"/index.php?option=com_comprofiler&task=login", form)
+		j.baseURL+"/index.php?option=com_comprofiler&task=login", form)
 	if err != nil {
 		panic(err)
 	}
@@ -239,17 +378,41 @@ func login() (username string) {
 	if strings.Contains(string(body), failed) {
 		panic(failed)
 	}
-	f, err := os.Create(loginInfoFile)
+	return jar.Cookies(j.site())
+}
+
+// sessionValid checks whether the judge still accepts the installed cookies.
+// A signed out page has the login form with the password field.
+func (j *onlineJudge) sessionValid() bool {
+	defer spin("Checking session")()
+	resp, err := http.Get(j.baseURL)
 	if err != nil {
 		panic(err)
 	}
-	defer f.Close()
-	info := loginInfo{
-		Username: username,
-		Cookies:  http.DefaultClient.Jar.Cookies(uvaURL),
-	}
-	if err := gob.NewEncoder(f).Encode(info); err != nil {
+	doc, err := goquery.NewDocumentFromResponse(resp)
+	if err != nil {
 		panic(err)
 	}
-	return
+	return doc.Find(`input[name="passwd"]`).Length() == 0
+}
+
+// ensureSession installs the cookies of the profile for a judge, and signs
+// in again if they have expired and the password was remembered.
+func ensureSession(j judge) loginInfo {
+	if j.site() == nil {
+		return loginInfo{}
+	}
+	profile := profileFor(j)
+	info := loadProfile(profile)
+	if j.sessionValid() {
+		return info
+	}
+	password, ok := savedPassword(profile, info.Username)
+	if !ok {
+		panic(sessionExpired)
+	}
+	cprintf(magenta, 0, "Session expired, signing in again as %s\n", info.Username)
+	info.Cookies = j.signIn(info.Username, password)
+	saveLoginInfo(profile, info)
+	return info
 }
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/urfave/cli"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Passwords saved with `uva user -l --remember` are kept out of the login
+// profiles, either in the desktop keyring through the Secret Service, or in
+// a file encrypted with a key derived from a passphrase.
+
+var (
+	credentialsFile = dataPath + "credentials.enc"
+	// revokedFile lists the passwords of profiles logged out while the
+	// encrypted file was locked, one profile/username per line.
+	revokedFile = dataPath + "credentials.revoked"
+)
+
+type credentialStore interface {
+	name() string
+	get(profile, username string) (password string, ok bool)
+	set(profile, username, password string)
+	remove(profile, username string)
+}
+
+// credentialStoreFor returns the store chosen by `credentials` in the config.
+func credentialStoreFor() credentialStore {
+	switch config.Credentials {
+	case "keyring":
+		if !keyringAvailable() {
+			panic("the Secret Service is not available, please install secret-tool (libsecret) or set credentials to file in config")
+		}
+		return keyringStore{}
+	case "file":
+		return &fileStore{}
+	case "none":
+		return nil
+	}
+	if keyringAvailable() {
+		return keyringStore{}
+	}
+	return &fileStore{}
+}
+
+// keyringStore talks to the Secret Service (GNOME Keyring, KWallet) with
+// the secret-tool command of libsecret.
+type keyringStore struct{}
+
+func keyringAvailable() bool {
+	_, err := exec.LookPath("secret-tool")
+	return err == nil && os.Getenv("DBUS_SESSION_BUS_ADDRESS") != ""
+}
+
+func keyringAttributes(profile, username string) []string {
+	return []string{"service", "uva-cli", "profile", profile, "username", username}
+}
+
+func (keyringStore) name() string {
+	return "Secret Service keyring"
+}
+
+func (keyringStore) get(profile, username string) (string, bool) {
+	out, err := exec.Command("secret-tool", append([]string{"lookup"}, keyringAttributes(profile, username)...)...).Output()
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			// not found
+			return "", false
+		}
+		panic(err)
+	}
+	return string(out), true
+}
+
+func (keyringStore) set(profile, username, password string) {
+	args := append([]string{"store", "--label=uva-cli " + profile}, keyringAttributes(profile, username)...)
+	cmd := exec.Command("secret-tool", args...)
+	// secret-tool reads the password from stdin, so it doesn't show up in ps
+	cmd.Stdin = strings.NewReader(password)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		panic(fmt.Sprintf("secret-tool: %s %s", err, out))
+	}
+}
+
+func (keyringStore) remove(profile, username string) {
+	exec.Command("secret-tool", append([]string{"clear"}, keyringAttributes(profile, username)...)...).Run()
+}
+
+// fileStore encrypts all passwords in one file with NaCl secretbox. The key
+// is derived from a passphrase with scrypt. The layout of the file is
+// "uva1", the salt, the nonce, and the sealed gob of the passwords.
+//
+// The file is unlocked at most once per command, as scrypt is slow on
+// purpose.
+type fileStore struct {
+	passwords map[string]string
+	key       *[32]byte
+	salt      []byte
+}
+
+const credentialsMagic = "uva1"
+
+func (*fileStore) name() string {
+	return "encrypted file " + credentialsFile
+}
+
+func passphrase(confirm bool) string {
+	if p := os.Getenv("UVA_PASSPHRASE"); p != "" {
+		return p
+	}
+	// stdin may carry the password of --password-stdin
+	if !stdinIsTerminal {
+		panic("a passphrase is required to unlock the credentials, please set $UVA_PASSPHRASE")
+	}
+	p := readPassword("Passphrase for saved passwords: ")
+	if confirm && readPassword("Repeat the passphrase: ") != p {
+		panic("passphrases do not match")
+	}
+	if p == "" {
+		panic("empty passphrase")
+	}
+	return p
+}
+
+func deriveKey(passphrase string, salt []byte) *[32]byte {
+	k, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		panic(err)
+	}
+	var key [32]byte
+	copy(key[:], k)
+	return &key
+}
+
+func credentialKey(profile, username string) string {
+	return profile + "/" + username
+}
+
+func loadRevoked() map[string]bool {
+	revoked := make(map[string]bool)
+	data, err := ioutil.ReadFile(revokedFile)
+	if os.IsNotExist(err) {
+		return revoked
+	} else if err != nil {
+		panic(err)
+	}
+	for _, key := range strings.Fields(string(data)) {
+		revoked[key] = true
+	}
+	return revoked
+}
+
+// revoke makes a saved password unusable without unlocking the file. It is
+// removed from the file the next time the file is saved.
+func revoke(key string) {
+	f, err := os.OpenFile(revokedFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintln(f, key); err != nil {
+		panic(err)
+	}
+}
+
+// load decrypts the passwords, with a new key and salt if the file does
+// not exist. Revoked passwords are dropped.
+func (s *fileStore) load() {
+	if s.passwords != nil {
+		return
+	}
+	passwords := make(map[string]string)
+	data, err := ioutil.ReadFile(credentialsFile)
+	if os.IsNotExist(err) {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			panic(err)
+		}
+		s.passwords, s.key, s.salt = passwords, deriveKey(passphrase(true), salt), salt
+		return
+	} else if err != nil {
+		panic(err)
+	}
+	if len(data) < len(credentialsMagic)+16+24 || string(data[:len(credentialsMagic)]) != credentialsMagic {
+		panic(credentialsFile + " is corrupt")
+	}
+	data = data[len(credentialsMagic):]
+	salt, data := data[:16], data[16:]
+	var nonce [24]byte
+	copy(nonce[:], data[:24])
+	key := deriveKey(passphrase(false), salt)
+	plain, ok := secretbox.Open(nil, data[24:], &nonce, key)
+	if !ok {
+		panic("wrong passphrase for " + credentialsFile)
+	}
+	if err := gob.NewDecoder(bytes.NewReader(plain)).Decode(&passwords); err != nil {
+		panic(err)
+	}
+	for k := range loadRevoked() {
+		delete(passwords, k)
+	}
+	s.passwords, s.key, s.salt = passwords, key, salt
+}
+
+func (s *fileStore) save() {
+	var plain bytes.Buffer
+	if err := gob.NewEncoder(&plain).Encode(s.passwords); err != nil {
+		panic(err)
+	}
+	var nonce [24]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		panic(err)
+	}
+	out := append([]byte(credentialsMagic), s.salt...)
+	out = append(out, nonce[:]...)
+	out = secretbox.Seal(out, plain.Bytes(), &nonce, s.key)
+	if err := ioutil.WriteFile(credentialsFile, out, 0600); err != nil {
+		panic(err)
+	}
+	// the revoked passwords were dropped by load
+	if err := os.Remove(revokedFile); err != nil && !os.IsNotExist(err) {
+		panic(err)
+	}
+}
+
+func (s *fileStore) get(profile, username string) (string, bool) {
+	if !exists(credentialsFile) || loadRevoked()[credentialKey(profile, username)] {
+		return "", false
+	}
+	s.load()
+	password, ok := s.passwords[credentialKey(profile, username)]
+	return password, ok
+}
+
+func (s *fileStore) set(profile, username, password string) {
+	s.load()
+	s.passwords[credentialKey(profile, username)] = password
+	s.save()
+}
+
+func (s *fileStore) remove(profile, username string) {
+	if !exists(credentialsFile) {
+		return
+	}
+	s.load()
+	delete(s.passwords, credentialKey(profile, username))
+	s.save()
+}
+
+// rememberPassword saves the password of a profile to sign in again later.
+func rememberPassword(profile, username, password string) {
+	store := credentialStoreFor()
+	if store == nil {
+		panic("saving passwords is disabled by credentials: none in config")
+	}
+	store.set(profile, username, password)
+	fmt.Printf("Saved the password in the %s\n", store.name())
+}
+
+// savedPassword returns the remembered password of a login.
+func savedPassword(profile, username string) (string, bool) {
+	store := credentialStoreFor()
+	if store == nil {
+		return "", false
+	}
+	return store.get(profile, username)
+}
+
+func rememberCommand(c *cli.Context) {
+	loadConfig()
+	profile := currentProfile()
+	info := loadLoginInfo()
+	password := os.Getenv("UVA_PASSWORD")
+	if password == "" {
+		password = readPassword(fmt.Sprintf("Password of %s: ", colored(info.Username, yellow, bold)))
+	}
+	// make sure the password works before saving it
+	info.Cookies = judgeByName(info.judge()).signIn(info.Username, password)
+	saveLoginInfo(profile, info)
+	rememberPassword(profile, info.Username, password)
+}
+
+// logoutPassword removes the saved password of a profile being logged out.
+// The encrypted file needs the passphrase, so the password is revoked
+// instead, and removed from the file when it is next saved.
+func logoutPassword(profile, username string) {
+	if config.Credentials != "file" && config.Credentials != "none" && keyringAvailable() {
+		keyringStore{}.remove(profile, username)
+	}
+	if exists(credentialsFile) {
+		revoke(credentialKey(profile, username))
+	}
+}
+
+func forgetCommand(c *cli.Context) {
+	loadConfig()
+	info := loadLoginInfo()
+	if store := credentialStoreFor(); store != nil {
+		store.remove(currentProfile(), info.Username)
+	}
+	fmt.Println("Forgot the password of", colored(info.Username, yellow, bold))
+}
+
+func credentialsCommand(c *cli.Context) {
+	loadConfig()
+	store := credentialStoreFor()
+	if store == nil {
+		fmt.Println("Saving passwords is disabled")
+		return
+	}
+	fmt.Println("Passwords are saved in the", store.name())
+	for _, profile := range listProfiles() {
+		var info loginInfo
+		readGob(loginInfoFile(profile), &info)
+		_, saved := store.get(profile, info.Username)
+		mark := colored(no, red, bold)
+		if saved {
+			mark = colored(yes, cyan, bold)
+		}
+		fmt.Printf("%s %s\t%s\n", mark, profile, colored(info.Username, yellow, 0))
+	}
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli"
+	"gopkg.in/yaml.v2"
+)
+
+// `uva export` writes the tests of a problem as a package for other tools:
+// kattis is the layout `uva test --package` reads, zip is the same in a zip
+// file as Kattis uploads it, and polygon is the layout of Codeforces Polygon
+// and testlib.
+
+// packageWriter stores the files of a package by their slash-separated path.
+type packageWriter interface {
+	write(name string, data []byte)
+}
+
+type dirWriter string
+
+func (dir dirWriter) write(name string, data []byte) {
+	file := filepath.Join(string(dir), filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		panic(err)
+	}
+	if err := ioutil.WriteFile(file, data, 0644); err != nil {
+		panic(err)
+	}
+}
+
+type zipWriter struct {
+	*zip.Writer
+}
+
+func (w zipWriter) write(name string, data []byte) {
+	f, err := w.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		panic(err)
+	}
+	if _, err := f.Write(data); err != nil {
+		panic(err)
+	}
+}
+
+// exportCases returns the samples, then the other tests: the ones from
+// udebug.com, and the STEM*.in files with a STEM*.ans in the current
+// directory. Duplicate inputs are dropped.
+func exportCases(info problemInfo) (samples, tests []testCase) {
+	if received, ok := receivedCases(info.ID); ok {
+		samples = received
+	} else {
+		input, answer := getSamples(info)
+		samples = append(samples, testCase{Input: input, Answer: answer})
+		input, answer = getTestData(info.ID)
+		tests = append(tests, testCase{Input: input, Answer: answer})
+	}
+
+	stem := strings.TrimSuffix(info.getFileName("in"), ".in")
+	files, err := filepath.Glob(stem + "*.in")
+	if err != nil {
+		panic(err)
+	}
+	for _, file := range files {
+		answer, err := ioutil.ReadFile(strings.TrimSuffix(file, ".in") + ".ans")
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			panic(err)
+		}
+		input, err := ioutil.ReadFile(file)
+		if err != nil {
+			panic(err)
+		}
+		tests = append(tests, testCase{Input: string(input), Answer: string(answer)})
+	}
+	samples, tests = uniqueCases(samples, tests)
+	if len(samples)+len(tests) == 0 {
+		panic("no test case to export")
+	}
+	return samples, tests
+}
+
+// uniqueCases drops the cases without an answer, and the ones whose input
+// came before, in the samples or the tests.
+func uniqueCases(samples, tests []testCase) (uniqueSamples, uniqueTests []testCase) {
+	seen := make(map[string]bool)
+	unique := func(cases []testCase) []testCase {
+		var r []testCase
+		for _, tc := range cases {
+			if tc.Answer != "" && !seen[tc.Input] {
+				seen[tc.Input] = true
+				r = append(r, tc)
+			}
+		}
+		return r
+	}
+	uniqueSamples = unique(samples)
+	return uniqueSamples, unique(tests)
+}
+
+func writeKattis(w packageWriter, info problemInfo, prob problemConfig, statement string, samples, tests []testCase, solution string) {
+	pkg := problemPackage{
+		Name:      info.Title,
+		SourceURL: info.url(),
+	}
+	pkg.Limits.TimeLimit = judgeTimeLimit(prob, info).Seconds()
+	if prob.Tolerance != 0 {
+		pkg.ValidatorFlags = "float_tolerance " + strconv.FormatFloat(prob.Tolerance, 'g', -1, 64)
+	}
+	data, err := yaml.Marshal(pkg)
+	if err != nil {
+		panic(err)
+	}
+	w.write("problem.yaml", data)
+	w.write("problem_statement/problem.en.md", []byte(statement))
+	groups := [][]testCase{samples, tests}
+	for i, group := range []string{"sample", "secret"} {
+		for j, tc := range groups[i] {
+			name := fmt.Sprintf("data/%s/%02d", group, j+1)
+			w.write(name+".in", []byte(tc.Input))
+			w.write(name+".ans", []byte(tc.Answer))
+		}
+	}
+	if solution != "" {
+		w.write("submissions/accepted/"+filepath.Base(solution), readSolution(solution))
+	}
+}
+
+type polygonProblem struct {
+	XMLName    xml.Name           `xml:"problem"`
+	ShortName  string             `xml:"short-name,attr"`
+	Revision   int                `xml:"revision,attr"`
+	Names      []polygonName      `xml:"names>name"`
+	Statements []polygonStatement `xml:"statements>statement"`
+	Testset    struct {
+		Name              string        `xml:"name,attr"`
+		TimeLimit         int64         `xml:"time-limit"`
+		TestCount         int           `xml:"test-count"`
+		InputPathPattern  string        `xml:"input-path-pattern"`
+		AnswerPathPattern string        `xml:"answer-path-pattern"`
+		Tests             []polygonTest `xml:"tests>test"`
+	} `xml:"judging>testset"`
+	Assets struct {
+		Checker polygonChecker `xml:"checker"`
+		// Solutions is nil to leave out the element.
+		Solutions *polygonSolutions `xml:"solutions"`
+	} `xml:"assets"`
+}
+
+// polygonChecker names one of the standard checkers of testlib.
+type polygonChecker struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type polygonName struct {
+	Language string `xml:"language,attr"`
+	Value    string `xml:"value,attr"`
+}
+
+type polygonStatement struct {
+	Charset  string `xml:"charset,attr"`
+	Language string `xml:"language,attr"`
+	Path     string `xml:"path,attr"`
+	Type     string `xml:"type,attr"`
+}
+
+type polygonTest struct {
+	Method string `xml:"method,attr"`
+	Sample bool   `xml:"sample,attr,omitempty"`
+}
+
+type polygonSolutions struct {
+	Solution []polygonSolution `xml:"solution"`
+}
+
+type polygonSolution struct {
+	Tag    string `xml:"tag,attr"`
+	Source struct {
+		Path string `xml:"path,attr"`
+		Type string `xml:"type,attr"`
+	} `xml:"source"`
+}
+
+// polygonLanguages maps file extensions to the languages of Polygon.
+var polygonLanguages = map[string]string{
+	"c":    "c.gcc",
+	"cc":   "cpp.g++17",
+	"cpp":  "cpp.g++17",
+	"java": "java8",
+	"pas":  "pas.fpc",
+	"py":   "python.3",
+}
+
+// polygonCheckerName returns the standard checker that compares like the
+// diff of `uva test`: tokens, or floating point numbers to about tolerance.
+func polygonCheckerName(tolerance float64) string {
+	switch {
+	case tolerance == 0:
+		return "std::wcmp.cpp"
+	case tolerance >= 1e-4:
+		return "std::rcmp4.cpp"
+	case tolerance >= 1e-6:
+		return "std::rcmp6.cpp"
+	}
+	return "std::rcmp9.cpp"
+}
+
+func writePolygon(w packageWriter, info problemInfo, prob problemConfig, statement string, samples, tests []testCase, solution string) {
+	p := polygonProblem{ShortName: strconv.Itoa(info.ID), Revision: 1}
+	p.Assets.Checker = polygonChecker{polygonCheckerName(prob.Tolerance), "testlib"}
+	p.Names = []polygonName{{"english", info.Title}}
+	p.Statements = []polygonStatement{{"UTF-8", "english", "statements/.html/english/problem.html", "text/html"}}
+	w.write("statements/.html/english/problem.html", []byte(statement))
+
+	t := &p.Testset
+	t.Name = "tests"
+	t.TimeLimit = judgeTimeLimit(prob, info).Milliseconds()
+	t.InputPathPattern = "tests/%02d"
+	t.AnswerPathPattern = "tests/%02d.a"
+	for i, tc := range append(append([]testCase{}, samples...), tests...) {
+		name := fmt.Sprintf("tests/%02d", i+1)
+		w.write(name, []byte(tc.Input))
+		w.write(name+".a", []byte(tc.Answer))
+		t.Tests = append(t.Tests, polygonTest{Method: "manual", Sample: i < len(samples)})
+	}
+	t.TestCount = len(t.Tests)
+
+	if solution != "" {
+		var s polygonSolution
+		s.Tag = "main"
+		s.Source.Path = "solutions/" + filepath.Base(solution)
+		ext := strings.TrimPrefix(filepath.Ext(solution), ".")
+		if s.Source.Type = polygonLanguages[ext]; s.Source.Type == "" {
+			s.Source.Type = ext
+		}
+		p.Assets.Solutions = &polygonSolutions{[]polygonSolution{s}}
+		w.write(s.Source.Path, readSolution(solution))
+	}
+
+	data, err := xml.MarshalIndent(p, "", "    ")
+	if err != nil {
+		panic(err)
+	}
+	w.write("problem.xml", append([]byte(xml.Header), append(data, '\n')...))
+}
+
+func readSolution(file string) []byte {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		panic(fmt.Errorf("reference solution: %s", err))
+	}
+	return data
+}
+
+// exportDirs are the directories written by the exports, which are cleared
+// before exporting again so that no test of an earlier export stays.
+var exportDirs = []string{"data", "tests", "statements", "submissions", "solutions", "problem_statement"}
+
+// isExport reports whether out looks like a package, and not something
+// given to -o by mistake.
+func isExport(out, format string) bool {
+	if format == "zip" {
+		data, err := ioutil.ReadFile(out)
+		return err == nil && bytes.HasPrefix(data, []byte("PK\x03\x04"))
+	}
+	return exists(filepath.Join(out, "problem.yaml")) || exists(filepath.Join(out, "problem.xml"))
+}
+
+func clearExport(dir string) {
+	for _, name := range exportDirs {
+		if err := os.RemoveAll(filepath.Join(dir, name)); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func export(c *cli.Context) {
+	if c.NArg() == 0 {
+		panic("problem ID required")
+	}
+	pid := parsePid(c.Args().First())
+	loadConfig()
+	info := getProblemInfo(pid)
+	prob := config.problem(pid)
+	// checkers of config follow no protocol of Kattis or testlib
+	if len(prob.Checker) != 0 {
+		panic(fmt.Sprintf("problem %d has a checker in config, which can not be exported", pid))
+	}
+	solution := c.String("solution")
+	if solution == "" {
+		solution = prob.Solution
+	}
+	stem := strings.TrimSuffix(info.getFileName("in"), ".in")
+
+	format := c.String("format")
+	var out string
+	switch format {
+	case "kattis":
+		out = stem + "-kattis"
+	case "polygon":
+		out = stem + "-polygon"
+	case "zip":
+		out = stem + ".zip"
+	default:
+		panic("unknown format " + format + ", use kattis, polygon or zip")
+	}
+	if c.String("o") != "" {
+		out = c.String("o")
+	}
+	if exists(out) {
+		if !c.Bool("force") {
+			panic(out + " already exists, use --force to overwrite it")
+		}
+		if !isExport(out, format) {
+			panic(out + " is not a package exported before, refusing to overwrite it")
+		}
+	}
+
+	samples, tests := exportCases(info)
+	text := statementText(getStatement(info))
+	if format != "zip" {
+		clearExport(out)
+	}
+	switch format {
+	case "kattis":
+		writeKattis(dirWriter(out), info, prob, renderMarkdown(info, text), samples, tests, solution)
+	case "polygon":
+		writePolygon(dirWriter(out), info, prob, renderHTML(info, text), samples, tests, solution)
+	case "zip":
+		var buf bytes.Buffer
+		w := zipWriter{zip.NewWriter(&buf)}
+		writeKattis(w, info, prob, renderMarkdown(info, text), samples, tests, solution)
+		if err := w.Close(); err != nil {
+			panic(err)
+		}
+		if err := ioutil.WriteFile(out, buf.Bytes(), 0644); err != nil {
+			panic(err)
+		}
+	}
+	fmt.Printf("Exported %d samples and %d tests to %s\n", len(samples), len(tests), colored(out, yellow, underline))
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var (
+	testSamples = []testCase{{Input: "1\n", Answer: "one\n"}}
+	testTests   = []testCase{{Input: "2\n", Answer: "two\n"}, {Input: "3\n", Answer: "three\n"}}
+)
+
+func TestUniqueCases(t *testing.T) {
+	samples, tests := uniqueCases(
+		[]testCase{{Input: "1\n", Answer: "1\n"}, {Input: "2\n"}, {Input: "1\n", Answer: "1\n"}},
+		[]testCase{{Input: "1\n", Answer: "1\n"}, {Input: "2\n", Answer: "2\n"}, {Input: "3\n", Answer: "3\n"}, {Input: "3\n", Answer: "4\n"}},
+	)
+	if want := []testCase{{Input: "1\n", Answer: "1\n"}}; !reflect.DeepEqual(samples, want) {
+		t.Errorf("samples = %q, want %q", samples, want)
+	}
+	if want := []testCase{{Input: "2\n", Answer: "2\n"}, {Input: "3\n", Answer: "3\n"}}; !reflect.DeepEqual(tests, want) {
+		t.Errorf("tests = %q, want %q", tests, want)
+	}
+}
+
+func TestExportKattis(t *testing.T) {
+	dir := t.TempDir()
+	info := problemInfo{ID: 100, TrueID: 36, Title: "The 3n + 1 problem"}
+	prob := problemConfig{TimeLimit: "2s", Tolerance: 1e-6}
+	writeKattis(dirWriter(dir), info, prob, "statement", testSamples, testTests, "")
+
+	pkg := readPackage(dir)
+	if pkg.Name != info.Title {
+		t.Errorf("name = %q, want %q", pkg.Name, info.Title)
+	}
+	got, _ := pkg.config(problemConfig{})
+	if got.TimeLimit != "2s" || got.Tolerance != 1e-6 {
+		t.Errorf("time limit %q and tolerance %g, want 2s and 1e-6", got.TimeLimit, got.Tolerance)
+	}
+	want := []testCase{
+		{"sample/01", "1\n", "one\n"},
+		{"secret/01", "2\n", "two\n"},
+		{"secret/02", "3\n", "three\n"},
+	}
+	if cases := pkg.cases(); !reflect.DeepEqual(cases, want) {
+		t.Errorf("cases = %q, want %q", cases, want)
+	}
+}
+
+func TestExportPolygon(t *testing.T) {
+	dir := t.TempDir()
+	info := problemInfo{ID: 100, TrueID: 36, Title: "The 3n + 1 problem"}
+	prob := problemConfig{TimeLimit: "1500ms", Tolerance: 1e-6}
+	writePolygon(dirWriter(dir), info, prob, "<p>statement</p>", testSamples, testTests, "")
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "problem.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p polygonProblem
+	if err := xml.Unmarshal(data, &p); err != nil {
+		t.Fatal(err)
+	}
+	ts := p.Testset
+	if ts.TimeLimit != 1500 || ts.TestCount != 3 || len(ts.Tests) != 3 || !ts.Tests[0].Sample || ts.Tests[1].Sample {
+		t.Errorf("testset = %+v", ts)
+	}
+	if p.Assets.Checker.Name != "std::rcmp6.cpp" {
+		t.Errorf("checker = %q, want std::rcmp6.cpp", p.Assets.Checker.Name)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "tests", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2*ts.TestCount {
+		t.Errorf("%d files in tests, want %d", len(files), 2*ts.TestCount)
+	}
+	for i, tc := range append(append([]testCase{}, testSamples...), testTests...) {
+		input, _ := ioutil.ReadFile(filepath.Join(dir, "tests", []string{"01", "02", "03"}[i]))
+		answer, _ := ioutil.ReadFile(filepath.Join(dir, "tests", []string{"01", "02", "03"}[i]+".a"))
+		if string(input) != tc.Input || string(answer) != tc.Answer {
+			t.Errorf("test %d = %q, %q, want %q, %q", i+1, input, answer, tc.Input, tc.Answer)
+		}
+	}
+}
+
+func TestPolygonCheckerName(t *testing.T) {
+	tests := []struct {
+		tolerance float64
+		want      string
+	}{
+		{0, "std::wcmp.cpp"},
+		{1e-3, "std::rcmp4.cpp"},
+		{1e-4, "std::rcmp4.cpp"},
+		{1e-6, "std::rcmp6.cpp"},
+		{1e-9, "std::rcmp9.cpp"},
+	}
+	for _, test := range tests {
+		if got := polygonCheckerName(test.tolerance); got != test.want {
+			t.Errorf("tolerance %g: got %s, want %s", test.tolerance, got, test.want)
+		}
+	}
+}
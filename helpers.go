@@ -2,16 +2,16 @@ package main
 
 import (
 	"fmt"
-	"io"
+	"io/ioutil"
+	"math"
 	"net/http"
 	"os"
 	"os/exec"
 	"regexp"
+	"runtime"
 	"strconv"
 	"strings"
 	"unicode/utf8"
-
-	yaml "gopkg.in/yaml.v2"
 )
 
 const (
@@ -28,6 +28,41 @@ func exists(file string) bool {
 	return !os.IsNotExist(err)
 }
 
+// writeNewFile refuses to overwrite an existing file unless force is set.
+func writeNewFile(file string, data []byte, force bool) {
+	if exists(file) && !force {
+		panic(file + " already exists, use --force to overwrite it")
+	}
+	if err := ioutil.WriteFile(file, data, 0644); err != nil {
+		panic(err)
+	}
+}
+
+// systemOpener returns the command that opens files and URLs with the
+// default application.
+func systemOpener() []string {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"open"}
+	case "windows":
+		return []string{"rundll32", "url.dll,FileProtocolHandler"}
+	}
+	return []string{"xdg-open"}
+}
+
+// openDetached starts a GUI program in a new session, so it neither blocks
+// the terminal nor gets killed with it.
+func openDetached(cmd []string, target string) {
+	c := exec.Command(cmd[0], append(cmd[1:], target)...)
+	detach(c)
+	if err := c.Start(); err != nil {
+		panic(err)
+	}
+	if err := c.Process.Release(); err != nil {
+		panic(err)
+	}
+}
+
 var symbol = regexp.MustCompile(`[^\w\s-]`)
 var spaces = regexp.MustCompile(`\s+`)
 var filename = regexp.MustCompile(`(\d+)\.([\w-]+)\.(\w+)`)
@@ -52,58 +87,44 @@ func (info problemInfo) getFileName(ext string) string {
 	return fmt.Sprintf("%d.%s.%s", info.ID, slug, ext)
 }
 
-func download(url, file, msg string) {
+// fetch downloads url, and fails unless the server responds with 200 OK.
+func fetch(url, msg string) (data []byte, contentType string, err error) {
 	defer spin(msg)()
 	resp, err := http.Get(url)
 	if err != nil {
-		panic(err)
+		return nil, "", err
 	}
 	defer resp.Body.Close()
-	f, err := os.Create(file)
-	if err != nil {
-		panic(err)
-	}
-	defer f.Close()
-	if _, err = io.Copy(f, resp.Body); err != nil {
-		panic(err)
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("GET %s: %s", url, resp.Status)
 	}
+	data, err = ioutil.ReadAll(resp.Body)
+	return data, resp.Header.Get("Content-Type"), err
 }
 
-var config struct {
-	Test map[string]struct {
-		Compile, Run []string
+// sameWord reports whether two words are equal, or are numbers whose
+// absolute or relative error is within tolerance.
+func sameWord(a, b string, tolerance float64) bool {
+	if a == b {
+		return true
 	}
-	Lang string
-}
-
-func loadConfig() {
-	configFile := dataPath + "config.yml"
-	if !exists(configFile) {
-		download("https://github.com/cshuaimin/uva/raw/master/config.yml", configFile, "Downloading default config.yml")
+	if tolerance == 0 {
+		return false
 	}
-	f, err := os.Open(configFile)
+	x, err := strconv.ParseFloat(a, 64)
 	if err != nil {
-		panic(err)
+		return false
 	}
-	if err = yaml.NewDecoder(f).Decode(&config); err != nil {
-		panic(err)
-	}
-}
-
-func renderCmd(cmd []string, sourceFile string) *exec.Cmd {
-	if len(cmd) > 0 {
-		for i, v := range cmd {
-			if v == "{}" {
-				cmd[i] = sourceFile
-			}
-		}
-		return exec.Command(cmd[0], cmd[1:]...)
+	y, err := strconv.ParseFloat(b, 64)
+	if err != nil {
+		return false
 	}
-	return nil
+	d := math.Abs(x - y)
+	return d <= tolerance || d <= tolerance*math.Abs(x)
 }
 
 // line-by-line diff
-func diff(text1, text2, label1, label2 string, sep string) (diff string, same bool) {
+func diff(text1, text2, label1, label2 string, sep string, tolerance float64) (diff string, same bool) {
 	lines1 := strings.Split(text1, "\n")
 	lines2 := strings.Split(text2, "\n")
 	same = true
@@ -122,7 +143,7 @@ func diff(text1, text2, label1, label2 string, sep string) (diff string, same bo
 		words2 := strings.Split(lines2[lineno], sep)
 		idx := 0
 		for ; idx < len(words1) && idx < len(words2); idx++ {
-			if words1[idx] != words2[idx] {
+			if !sameWord(words1[idx], words2[idx], tolerance) {
 				same = false
 				// this changes the string length
 				words1[idx] = colored(words1[idx], green, 0)
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// submission is a line of history.jsonl. A submission is recorded when it
+// is sent, and again when its verdict is known; the last line wins.
+type submission struct {
+	ID      string    `json:"id"`
+	Problem int       `json:"problem"`
+	File    string    `json:"file"`
+	Verdict string    `json:"verdict,omitempty"`
+	Runtime string    `json:"runtime,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+func recordSubmission(s submission) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		panic(err)
+	}
+	f, err := os.OpenFile(historyFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		panic(err)
+	}
+}
+
+// loadHistory returns the submissions by ID.
+func loadHistory() map[string]submission {
+	history := make(map[string]submission)
+	f, err := os.Open(historyFile)
+	if os.IsNotExist(err) {
+		return history
+	} else if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		var s submission
+		if err := json.Unmarshal(scanner.Bytes(), &s); err != nil {
+			panic(fmt.Errorf("%s:%d: %s", historyFile, n, err))
+		}
+		history[s.ID] = s
+	}
+	if err := scanner.Err(); err != nil {
+		panic(err)
+	}
+	return history
+}
+
+func sortSubmissions(subs []submission) {
+	sort.Slice(subs, func(i, j int) bool {
+		return subs[i].Time.Before(subs[j].Time)
+	})
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+)
+
+// judge is a site to solve problems on. The judge of a problem is chosen by
+// `judge` in config, which can be set per problem.
+type judge interface {
+	// name is the key of the judge in config, e.g. uva.
+	name() string
+	// site is where the session cookies belong, nil if there is no login.
+	site() *url.URL
+	problems() map[int]problemInfo
+	problemURL(info problemInfo) string
+	// statementURLs are tried in order to download the description, and
+	// may point to a PDF or an HTML page.
+	statementURLs(info problemInfo) []string
+	udebugURL(pid int) string
+	signIn(username, password string) []*http.Cookie
+	sessionValid() bool
+	// submit sends a source file with the given extension, and returns the
+	// submission ID.
+	submit(info problemInfo, file, ext string) string
+	// result returns the status of a submission, which is one of
+	// pendingResults until it is judged.
+	result(sid string) (verdict, runTime string, err error)
+}
+
+const defaultJudge = "uva"
+
+var (
+	uvaJudge = &onlineJudge{
+		key:          "uva",
+		title:        "onlinejudge.org (UVa)",
+		baseURL:      "https://onlinejudge.org",
+		categories:   []int{1, 2},
+		problemsFile: problemsInfoFile,
+		udebug:       "UVa",
+		uhunt:        true,
+	}
+	// The ICPC Live Archive runs the same software as UVa.
+	liveArchive = &onlineJudge{
+		key:          "live-archive",
+		title:        "ICPC Live Archive",
+		baseURL:      "https://icpcarchive.ecs.baylor.edu",
+		categories:   []int{1},
+		problemsFile: dataPath + "problems-info.live-archive.gob",
+		udebug:       "LiveArchive",
+	}
+)
+
+func judgeNames() []string {
+	names := []string{uvaJudge.key, liveArchive.key, localJudgeName}
+	sort.Strings(names)
+	return names
+}
+
+func knownJudge(name string) bool {
+	for _, n := range judgeNames() {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// judgeByName returns a judge by its key in config, or the default one if
+// name is empty.
+func judgeByName(name string) judge {
+	if name == "" {
+		name = config.Judge
+	}
+	if name == "" && config.JudgeURL != "" {
+		name = localJudgeName
+	}
+	switch name {
+	case "", uvaJudge.key:
+		return uvaJudge
+	case liveArchive.key:
+		return liveArchive
+	case localJudgeName:
+		if config.JudgeURL == "" {
+			panic("judge local requires judge_url in config")
+		}
+		return localJudge{uvaJudge}
+	}
+	panic(fmt.Sprintf("unknown judge %s", name))
+}
+
+// judgeFor returns the judge of a problem.
+func judgeFor(pid int) judge {
+	if pid >= receivedBaseID {
+		return receivedJudge{}
+	}
+	return judgeByName(config.problem(pid).Judge)
+}
+
+const localJudgeName = "local"
+
+// localJudge submits to the `uva judge-server` at judge_url, which judges
+// the problems of UVa.
+type localJudge struct {
+	*onlineJudge
+}
+
+func (localJudge) name() string {
+	return localJudgeName
+}
+
+func (localJudge) site() *url.URL {
+	return nil
+}
+
+func (localJudge) signIn(username, password string) []*http.Cookie {
+	panic("the judge server needs no login")
+}
+
+func (localJudge) sessionValid() bool {
+	return true
+}
+
+func (localJudge) submit(info problemInfo, file, ext string) string {
+	return submitToServer(info.ID, file, ext)
+}
+
+func (localJudge) result(sid string) (verdict, runTime string, err error) {
+	return serverResult(sid)
+}
@@ -12,10 +12,31 @@ func main() {
 	app.UsageText = "uva [command]"
 	app.Version = "0.4.0"
 
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:   "profile",
+			Usage:  "use the login of this profile",
+			EnvVar: "UVA_PROFILE",
+		},
+	}
+	app.Before = func(c *cli.Context) error {
+		selectedProfile = c.GlobalString("profile")
+		return nil
+	}
+
 	loadCookies := func(c *cli.Context) error {
 		loadLoginInfo()
 		return nil
 	}
+	checkSession := func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			return nil
+		}
+		loadConfig()
+		pid, _, _ := parseFilename(c.Args().First())
+		ensureSession(judgeFor(pid))
+		return nil
+	}
 
 	app.Commands = []cli.Command{
 		{
@@ -30,17 +51,74 @@ func main() {
 					Name:  "L",
 					Usage: "user logout",
 				},
+				cli.BoolFlag{
+					Name:  "remember",
+					Usage: "with -l, save the password in the keyring or an encrypted file to sign in again when the session expires",
+				},
+				cli.StringFlag{
+					Name:  "username",
+					Usage: "with -l, the username instead of prompting for it",
+				},
+				cli.BoolFlag{
+					Name:  "password-stdin",
+					Usage: "with -l, read the password from stdin",
+				},
+				cli.StringFlag{
+					Name:  "profile",
+					Usage: "the profile to log in or out",
+				},
+				cli.StringFlag{
+					Name:  "judge",
+					Usage: "with -l, the judge to log in to: uva or live-archive, judge in config by default",
+				},
+				cli.StringFlag{
+					Name:  "switch",
+					Usage: "use this profile by default",
+				},
+				cli.BoolFlag{
+					Name:  "list",
+					Usage: "list profiles",
+				},
 			},
 			Action: user,
+			Subcommands: []cli.Command{
+				{
+					Name:   "remember",
+					Usage:  "save the password of the profile to sign in again when the session expires",
+					Action: rememberCommand,
+				},
+				{
+					Name:   "forget",
+					Usage:  "remove the saved password of the profile",
+					Action: forgetCommand,
+				},
+				{
+					Name:   "credentials",
+					Usage:  "show where passwords are saved, and which profiles have one",
+					Action: credentialsCommand,
+				},
+			},
 		},
 		{
 			Name:      "show",
 			Usage:     "show problem by id",
-			UsageText: "uva show ID",
+			UsageText: "uva show [--format md|html|text] ID",
 			Flags: []cli.Flag{
 				cli.BoolFlag{
 					Name:  "g",
-					Usage: "open the pdf in a GUI viewer",
+					Usage: "open the pdf in $UVA_PDF_VIEWER, the viewer in config, or the default viewer",
+				},
+				cli.BoolFlag{
+					Name:  "web",
+					Usage: "open the problem page on the judge in a browser",
+				},
+				cli.BoolFlag{
+					Name:  "udebug",
+					Usage: "open the problem page on udebug.com in a browser",
+				},
+				cli.StringFlag{
+					Name:  "format",
+					Usage: "print the problem as md, html or text instead of paging it",
 				},
 			},
 			Action: show,
@@ -55,20 +133,233 @@ func main() {
 					Name:  "lang",
 					Usage: "file extension",
 				},
+				cli.BoolFlag{
+					Name:  "samples, s",
+					Usage: "also write the sample input and output to STEM.in and STEM.ans",
+				},
+				cli.BoolFlag{
+					Name:  "force, f",
+					Usage: "overwrite existing files",
+				},
 			},
 			Action: touch,
 		},
 		{
 			Name:      "submit",
 			Usage:     "submit code",
-			UsageText: "uva submit FILE",
-			Action:    submitAndShowResult,
-			Before:    loadCookies,
+			UsageText: "uva submit [--timeout 5m] FILE",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "timeout",
+					Usage: "how long to wait for the verdict, overrides result_timeout in config",
+				},
+			},
+			Action: submitAndShowResult,
+			Before: checkSession,
+		},
+		{
+			Name:      "next",
+			Usage:     "suggest unsolved problems to practice",
+			UsageText: "uva next [--volume N] [--tag TAG] [-n 10]",
+			Flags: []cli.Flag{
+				cli.IntFlag{
+					Name:  "volume",
+					Usage: "only problems of this volume, e.g. 100 for 10000-10099",
+				},
+				cli.StringFlag{
+					Name:  "tag",
+					Usage: "only problems with this tag in tags.yml",
+				},
+				cli.IntFlag{
+					Name:  "n",
+					Value: 10,
+					Usage: "number of problems to show",
+				},
+				cli.StringFlag{
+					Name:  "user",
+					Usage: "suggest for this user instead of yourself",
+				},
+			},
+			Action: next,
+		},
+		{
+			Name:      "note",
+			Usage:     "edit the Markdown note of a problem in $EDITOR",
+			UsageText: "uva note ID",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "print, p",
+					Usage: "print the note instead",
+				},
+			},
+			Action: note,
+		},
+		{
+			Name:      "tag",
+			Usage:     "add topic tags to a problem, or show them",
+			UsageText: "uva tag [-d] ID [TAG...]",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "d",
+					Usage: "remove the tags instead",
+				},
+			},
+			Action: tag,
+		},
+		{
+			Name:      "list",
+			Usage:     "manage named practice lists",
+			UsageText: "uva list [add|rm|show] NAME [ID...]",
+			Action:    listLists,
+			Subcommands: []cli.Command{
+				{
+					Name:      "add",
+					Usage:     "add problems to a list",
+					UsageText: "uva list add NAME ID...",
+					Action:    listAdd,
+				},
+				{
+					Name:      "rm",
+					Usage:     "remove problems from a list, or the whole list",
+					UsageText: "uva list rm NAME [ID...]",
+					Action:    listRemove,
+				},
+				{
+					Name:      "show",
+					Usage:     "show the problems of a list",
+					UsageText: "uva list show NAME",
+					Action:    listShow,
+				},
+			},
+		},
+		{
+			Name:      "book",
+			Usage:     "practice with the exercises of Competitive Programming",
+			UsageText: "uva book [import|next]",
+			Action:    bookProgress,
+			Subcommands: []cli.Command{
+				{
+					Name:  "import",
+					Usage: "import the exercises from uHunt as lists cpN-ch1, cpN-ch2...",
+					Flags: []cli.Flag{
+						cli.IntFlag{
+							Name:  "edition",
+							Value: 3,
+							Usage: "edition of the book",
+						},
+					},
+					Action: bookImport,
+				},
+				{
+					Name:      "next",
+					Usage:     "create the source file of the next unsolved problem",
+					UsageText: "uva book next [CHAPTER]",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "lang",
+							Usage: "file extension",
+						},
+						cli.BoolFlag{
+							Name:  "samples, s",
+							Usage: "also write the sample input and output",
+						},
+					},
+					Action: bookNext,
+				},
+			},
+		},
+		{
+			Name:      "contest",
+			Usage:     "virtual contest with an ICPC-style scoreboard",
+			UsageText: "uva contest [start|status|stop]",
+			Action:    contestStatus,
+			Subcommands: []cli.Command{
+				{
+					Name:      "start",
+					Usage:     "start a contest",
+					UsageText: "uva contest start --problems 100,101,10041 --duration 5h",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "problems",
+							Usage: "comma separated problem IDs",
+						},
+						cli.StringFlag{
+							Name:  "duration",
+							Value: "5h",
+							Usage: "length of the contest",
+						},
+						cli.BoolFlag{
+							Name:  "force, f",
+							Usage: "replace the running contest",
+						},
+					},
+					Action: contestStart,
+				},
+				{
+					Name:    "status",
+					Aliases: []string{"scoreboard"},
+					Usage:   "print the scoreboard",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "watch, w",
+							Usage: "refresh the scoreboard every second until the end",
+						},
+					},
+					Action: contestStatus,
+				},
+				{
+					Name:   "stop",
+					Usage:  "end the contest and print the final scoreboard",
+					Action: contestStop,
+				},
+			},
+		},
+		{
+			Name:      "judge-server",
+			Usage:     "judge submissions locally over HTTP, for team practice",
+			UsageText: "uva judge-server [--addr 127.0.0.1:8080] [--workers 2]",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "addr",
+					Value: "127.0.0.1:8080",
+					Usage: "address to listen on, e.g. :8080 to accept other machines",
+				},
+				cli.IntFlag{
+					Name:  "workers",
+					Value: 2,
+					Usage: "number of submissions judged at the same time",
+				},
+			},
+			Action: judgeServerCommand,
+		},
+		{
+			Name:      "search",
+			Usage:     "search problems by title, tag, list or note",
+			UsageText: "uva search WORD...",
+			Action:    search,
+		},
+		{
+			Name:      "stats",
+			Usage:     "show the solved problems and verdicts of a user on uHunt",
+			UsageText: "uva stats [USER]",
+			Action:    stats,
+		},
+		{
+			Name:      "status",
+			Usage:     "show the verdict of a submission",
+			UsageText: "uva status [--timeout 5m] SID",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "timeout",
+					Usage: "how long to wait if it is still being judged",
+				},
+			},
+			Action: status,
 		},
 		{
 			Name:      "test",
 			Usage:     "test code locally",
-			UsageText: "uva test FILE",
+			UsageText: "uva test [--package DIR] FILE",
 			Flags: []cli.Flag{
 				cli.StringFlag{
 					Name:  "i",
@@ -82,9 +373,59 @@ func main() {
 					Name:  "b",
 					Usage: "compare each line of output with the answer byte-by-byte",
 				},
+				cli.BoolFlag{
+					Name:  "s",
+					Usage: "test with the sample in the problem description",
+				},
+				cli.StringFlag{
+					Name:  "package",
+					Usage: "test with the cases, limits and output validator of a problem package in the Kattis format",
+				},
 			},
 			Action: testProgram,
 		},
+		{
+			Name:      "listen",
+			Usage:     "receive problems and tests from the Competitive Companion browser extension",
+			UsageText: "uva listen [--port 27121] [--lang cpp]",
+			Flags: []cli.Flag{
+				cli.IntFlag{
+					Name:  "port",
+					Value: companionPort,
+					Usage: "the port Competitive Companion sends to",
+				},
+				cli.StringFlag{
+					Name:  "lang",
+					Usage: "file extension of the created source files",
+				},
+			},
+			Action: listen,
+		},
+		{
+			Name:      "export",
+			Usage:     "export the test data of a problem as a Kattis or Polygon package",
+			UsageText: "uva export [--format kattis|polygon|zip] [--solution FILE] ID",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "format",
+					Value: "kattis",
+					Usage: "kattis, polygon, or zip for a zipped Kattis package",
+				},
+				cli.StringFlag{
+					Name:  "solution",
+					Usage: "the reference solution to include, solution of the problem in config by default",
+				},
+				cli.StringFlag{
+					Name:  "o",
+					Usage: "where to write the package, STEM-FORMAT or STEM.zip by default",
+				},
+				cli.BoolFlag{
+					Name:  "force, f",
+					Usage: "overwrite existing files",
+				},
+			},
+			Action: export,
+		},
 		{
 			Name:      "dump",
 			Usage:     "dump test cases to files",
@@ -103,6 +444,42 @@ func main() {
 			},
 			Action: dump,
 		},
+		{
+			Name:      "samples",
+			Usage:     "print or dump the sample input and output of a problem",
+			UsageText: "uva samples ID",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "i",
+					Usage: "file to store the sample input",
+				},
+				cli.StringFlag{
+					Name:  "a",
+					Usage: "file to store the sample output",
+				},
+			},
+			Action: samples,
+		},
+		{
+			Name:      "config",
+			Usage:     "print the effective configuration",
+			UsageText: "uva config",
+			Action:    showConfig,
+			Subcommands: []cli.Command{
+				{
+					Name:      "init",
+					Usage:     "write the default config.yml",
+					UsageText: "uva config init",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "force",
+							Usage: "overwrite the existing config.yml",
+						},
+					},
+					Action: initConfig,
+				},
+			},
+		},
 	}
 
 	defer func() {
@@ -113,13 +490,14 @@ func main() {
 	}()
 
 	// make data directories
-	for _, path := range []string{dataPath, pdfPath, testDataPath} {
+	for _, path := range []string{dataPath, pdfPath, testDataPath, templatePath, profilesPath, notesPath} {
 		if !exists(path) {
 			if err := os.Mkdir(path, 0755); err != nil {
 				panic(err)
 			}
 		}
 	}
+	migrateLoginInfo()
 
 	app.Run(os.Args)
 }
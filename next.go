@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/urfave/cli"
+)
+
+// popularity is the number of users who solved a problem, which tells how
+// easy it is.
+func popularity(info problemInfo) float64 {
+	if info.DACU != 0 {
+		return float64(info.DACU)
+	}
+	// the judge's pages only have the accepted submissions
+	return float64(info.TotalSubmissions) * float64(info.Percentage) / 100
+}
+
+// recentLevel is the median popularity of the last 10 problems the user
+// solved, or 0 if there is none.
+func recentLevel(user uhuntUser, byTrueID map[int]problemInfo) float64 {
+	var levels []float64
+	seen := make(map[int]bool)
+	for i := len(user.Submissions) - 1; i >= 0 && len(levels) < 10; i-- {
+		s := user.Submissions[i]
+		if s.Verdict != verdictAccepted || seen[s.TrueID] {
+			continue
+		}
+		seen[s.TrueID] = true
+		if p, ok := byTrueID[s.TrueID]; ok {
+			levels = append(levels, popularity(p))
+		}
+	}
+	if len(levels) == 0 {
+		return 0
+	}
+	sort.Float64s(levels)
+	return levels[len(levels)/2]
+}
+
+// rankProblems sorts problems by how well they suit a user at the given
+// level: a bit harder than the problems solved recently, with a high
+// acceptance rate. Beginners get the most solved problems first.
+func rankProblems(problems []problemInfo, level float64) {
+	target := level * 0.8
+	scores := make(map[int]float64, len(problems))
+	for _, p := range problems {
+		pop := math.Max(popularity(p), 1)
+		var distance float64
+		if target > 0 {
+			distance = math.Abs(math.Log(pop) - math.Log(target))
+		} else {
+			distance = -math.Log(pop)
+		}
+		scores[p.ID] = distance - float64(p.Percentage)/100
+	}
+	sort.Slice(problems, func(i, j int) bool {
+		a, b := scores[problems[i].ID], scores[problems[j].ID]
+		if a != b {
+			return a < b
+		}
+		return problems[i].ID < problems[j].ID
+	})
+}
+
+func printProblems(problems []problemInfo, n int) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	for i, p := range problems {
+		if i == n {
+			break
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%.1f %%\t%.0f solved\n",
+			colored(fmt.Sprint(p.ID), yellow, bold), p.Title, p.Percentage, popularity(p))
+	}
+	w.Flush()
+}
+
+func next(c *cli.Context) {
+	loadConfig()
+	volume := c.Int("volume")
+	tag := c.String("tag")
+	var tags map[int][]string
+	if tag != "" {
+		tags = loadTags()
+	}
+	problems := loadProblems()
+	user := uhuntUserInfo(defaultUsername(c.String("user")))
+	accepted, tried := user.solved()
+
+	byTrueID := make(map[int]problemInfo, len(problems))
+	var attempted, fresh []problemInfo
+	for _, p := range problems {
+		byTrueID[p.TrueID] = p
+		if !p.available() || accepted[p.TrueID] {
+			continue
+		}
+		if volume != 0 && p.ID/100 != volume {
+			continue
+		}
+		if tag != "" && !hasTag(tags[p.ID], tag) {
+			continue
+		}
+		if tried[p.TrueID] {
+			attempted = append(attempted, p)
+		} else {
+			fresh = append(fresh, p)
+		}
+	}
+	if len(attempted) == 0 && len(fresh) == 0 {
+		if tag != "" {
+			panic(fmt.Sprintf("no unsolved problem tagged %s, tags are read from %s", tag, tagsFile))
+		}
+		panic("no unsolved problem found")
+	}
+
+	level := recentLevel(user, byTrueID)
+	rankProblems(attempted, level)
+	rankProblems(fresh, level)
+	n := c.Int("n")
+	if len(attempted) != 0 {
+		fmt.Println(colored("Tried before", red, bold))
+		printProblems(attempted, n)
+		fmt.Println()
+	}
+	if len(fresh) != 0 {
+		fmt.Println(colored("New problems", cyan, bold))
+		printProblems(fresh, n)
+	}
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// What we learned about problems is kept in the data directory: a Markdown
+// note per problem, topic tags in tags.yml and named practice lists in
+// lists.yml.
+
+func parsePid(s string) int {
+	pid, err := strconv.Atoi(s)
+	if err != nil || pid <= 0 {
+		panic("invalid problem id " + s)
+	}
+	return pid
+}
+
+// readYaml decodes a file of the data directory, leaving v as it is if the
+// file does not exist.
+func readYaml(file string, v interface{}) {
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return
+	} else if err != nil {
+		panic(err)
+	}
+	if err := yaml.UnmarshalStrict(data, v); err != nil {
+		panic(fmt.Errorf("%s: %s", file, err))
+	}
+}
+
+func writeYaml(file string, v interface{}) {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	if err := ioutil.WriteFile(file, data, 0644); err != nil {
+		panic(err)
+	}
+}
+
+// loadTags reads the topic tags of problems from tags.yml, e.g.
+//
+//	10041: [math, median]
+func loadTags() map[int][]string {
+	tags := make(map[int][]string)
+	readYaml(tagsFile, &tags)
+	return tags
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadLists reads the practice lists from lists.yml, e.g.
+//
+//	cp3-ch4: [10041, 11235]
+func loadLists() map[string][]int {
+	lists := make(map[string][]int)
+	readYaml(listsFile, &lists)
+	return lists
+}
+
+func sortedListNames(lists map[string][]int) []string {
+	names := make([]string, 0, len(lists))
+	for name := range lists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// listsOf returns the names of the lists a problem is in.
+func listsOf(lists map[string][]int, pid int) []string {
+	var names []string
+	for _, name := range sortedListNames(lists) {
+		if containsInt(lists[name], pid) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func containsInt(a []int, x int) bool {
+	for _, v := range a {
+		if v == x {
+			return true
+		}
+	}
+	return false
+}
+
+func noteFile(pid int) string {
+	return fmt.Sprintf("%s%d.md", notesPath, pid)
+}
+
+func readNote(pid int) string {
+	data, err := ioutil.ReadFile(noteFile(pid))
+	if os.IsNotExist(err) {
+		return ""
+	} else if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
+
+// loadNotes reads all notes, keyed by problem ID.
+func loadNotes() map[int]string {
+	notes := make(map[int]string)
+	entries, err := ioutil.ReadDir(notesPath)
+	if os.IsNotExist(err) {
+		return notes
+	} else if err != nil {
+		panic(err)
+	}
+	for _, fi := range entries {
+		pid, err := strconv.Atoi(strings.TrimSuffix(fi.Name(), ".md"))
+		if err != nil || fi.IsDir() || !strings.HasSuffix(fi.Name(), ".md") {
+			continue
+		}
+		notes[pid] = readNote(pid)
+	}
+	return notes
+}
+
+func note(c *cli.Context) {
+	if c.NArg() == 0 {
+		panic("problem id required")
+	}
+	pid := parsePid(c.Args().First())
+	file := noteFile(pid)
+	if c.Bool("print") {
+		fmt.Print(readNote(pid))
+		return
+	}
+	if !exists(file) {
+		loadConfig()
+		info := getProblemInfo(pid)
+		header := fmt.Sprintf("# %d - %s\n\n", info.ID, info.Title)
+		if err := ioutil.WriteFile(file, []byte(header), 0644); err != nil {
+			panic(err)
+		}
+		// don't keep notes that were opened but not written
+		defer func() {
+			if readNote(pid) == header {
+				os.Remove(file)
+			}
+		}()
+	}
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+	}
+	cmd := exec.Command(editor[0], append(editor[1:], file)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		panic(err)
+	}
+}
+
+func tag(c *cli.Context) {
+	if c.NArg() == 0 {
+		panic("problem id required")
+	}
+	loadConfig()
+	pid := parsePid(c.Args().First())
+	tags := loadTags()
+	for _, t := range c.Args().Tail() {
+		if c.Bool("d") {
+			var kept []string
+			for _, old := range tags[pid] {
+				if !strings.EqualFold(old, t) {
+					kept = append(kept, old)
+				}
+			}
+			tags[pid] = kept
+		} else if !hasTag(tags[pid], t) {
+			tags[pid] = append(tags[pid], t)
+		}
+	}
+	if len(tags[pid]) == 0 {
+		delete(tags, pid)
+	}
+	if c.NArg() > 1 {
+		writeYaml(tagsFile, tags)
+	}
+	fmt.Println(strings.Join(tags[pid], " "))
+}
+
+func listLists(c *cli.Context) {
+	lists := loadLists()
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	for _, name := range sortedListNames(lists) {
+		fmt.Fprintf(w, "%s\t%d problems\n", colored(name, yellow, bold), len(lists[name]))
+	}
+	w.Flush()
+}
+
+func listAdd(c *cli.Context) {
+	if c.NArg() < 2 {
+		panic("list name and problem ids required")
+	}
+	loadConfig()
+	name := c.Args().First()
+	lists := loadLists()
+	for _, arg := range c.Args().Tail() {
+		pid := parsePid(arg)
+		if !containsInt(lists[name], pid) {
+			lists[name] = append(lists[name], pid)
+		}
+	}
+	writeYaml(listsFile, lists)
+}
+
+func listRemove(c *cli.Context) {
+	if c.NArg() == 0 {
+		panic("list name required")
+	}
+	loadConfig()
+	name := c.Args().First()
+	lists := loadLists()
+	if _, ok := lists[name]; !ok {
+		panic("no list named " + name)
+	}
+	if c.NArg() == 1 {
+		delete(lists, name)
+	} else {
+		remove := make(map[int]bool)
+		for _, arg := range c.Args().Tail() {
+			remove[parsePid(arg)] = true
+		}
+		var kept []int
+		for _, pid := range lists[name] {
+			if !remove[pid] {
+				kept = append(kept, pid)
+			}
+		}
+		lists[name] = kept
+	}
+	writeYaml(listsFile, lists)
+}
+
+func listShow(c *cli.Context) {
+	if c.NArg() == 0 {
+		panic("list name required")
+	}
+	loadConfig()
+	name := c.Args().First()
+	pids, ok := loadLists()[name]
+	if !ok {
+		panic("no list named " + name)
+	}
+	problems := loadProblems()
+	tags := loadTags()
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	for _, pid := range pids {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", colored(strconv.Itoa(pid), yellow, bold), problems[pid].Title, strings.Join(tags[pid], " "))
+	}
+	w.Flush()
+}
+
+// search finds problems whose ID, title, tags, lists or note contain all
+// the words of the query.
+func search(c *cli.Context) {
+	if c.NArg() == 0 {
+		panic("search words required")
+	}
+	loadConfig()
+	var words []string
+	for _, arg := range c.Args() {
+		words = append(words, strings.Fields(strings.ToLower(arg))...)
+	}
+	problems := loadProblems()
+	tags := loadTags()
+	lists := loadLists()
+	notes := loadNotes()
+	ids := make([]int, 0, len(problems))
+	for pid := range problems {
+		ids = append(ids, pid)
+	}
+	sort.Ints(ids)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	found := false
+	for _, pid := range ids {
+		info := problems[pid]
+		text := strings.ToLower(fmt.Sprintf("%d %s %s %s %s", pid, info.Title,
+			strings.Join(tags[pid], " "), strings.Join(listsOf(lists, pid), " "), notes[pid]))
+		match := true
+		for _, word := range words {
+			if !strings.Contains(text, word) {
+				match = false
+				break
+			}
+		}
+		if match {
+			found = true
+			fmt.Fprintf(w, "%s\t%s\t%s\n", colored(strconv.Itoa(pid), yellow, bold), info.Title, strings.Join(tags[pid], " "))
+		}
+	}
+	w.Flush()
+	if !found {
+		panic("no problem found")
+	}
+}
+
+// renderNotes formats the tags, lists and note of a problem for `uva show`,
+// or returns "" if there is none.
+func renderNotes(pid int) string {
+	tags := loadTags()[pid]
+	lists := listsOf(loadLists(), pid)
+	note := readNote(pid)
+	if len(tags) == 0 && len(lists) == 0 && note == "" {
+		return ""
+	}
+	var buf strings.Builder
+	buf.WriteString("\n" + colored("Notes", white, bold) + "\n")
+	if len(tags) != 0 {
+		buf.WriteString(indent + "Tags: " + strings.Join(tags, ", ") + "\n")
+	}
+	if len(lists) != 0 {
+		buf.WriteString(indent + "Lists: " + strings.Join(lists, ", ") + "\n")
+	}
+	if note != "" {
+		buf.WriteString("\n")
+		for _, line := range strings.Split(strings.TrimRight(note, "\n"), "\n") {
+			buf.WriteString(strings.TrimRight(indent+line, " ") + "\n")
+		}
+	}
+	return buf.String()
+}
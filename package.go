@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// A problem package in the Kattis format, as used by ICPC regionals:
+//
+//	problem.yaml                 limits and validator_flags
+//	data/sample/*.in, *.ans      the sample tests
+//	data/secret/*.in, *.ans      the judge's tests, maybe in subdirectories
+//	output_validators/NAME/      a custom checker, if the answer is not unique
+type problemPackage struct {
+	dir       string
+	Name      string
+	SourceURL string `yaml:"source_url,omitempty"`
+	Limits    struct {
+		// TimeLimit is in seconds.
+		TimeLimit float64 `yaml:"time_limit,omitempty"`
+	} `yaml:",omitempty"`
+	// ValidatorFlags are passed to the output validator, or tell the
+	// default one how to compare, e.g. "float_tolerance 1e-6".
+	ValidatorFlags string `yaml:"validator_flags,omitempty"`
+}
+
+func readPackage(dir string) problemPackage {
+	pkg := problemPackage{dir: dir}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "problem.yaml"))
+	if os.IsNotExist(err) {
+		panic(fmt.Sprintf("%s is not a problem package, problem.yaml not found", dir))
+	} else if err != nil {
+		panic(err)
+	}
+	// packages have many keys we do not use
+	if err := yaml.Unmarshal(data, &pkg); err != nil {
+		panic(fmt.Errorf("%s: %s", filepath.Join(dir, "problem.yaml"), err))
+	}
+	// older packages keep the time limit in .timelimit
+	if data, err := ioutil.ReadFile(filepath.Join(dir, ".timelimit")); err == nil && pkg.Limits.TimeLimit == 0 {
+		pkg.Limits.TimeLimit, _ = strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+	}
+	return pkg
+}
+
+// cases returns the sample tests, then the secret ones, named by their path
+// in data, e.g. secret/01-small.
+func (pkg problemPackage) cases() []testCase {
+	var cases []testCase
+	for _, group := range []string{"sample", "secret"} {
+		root := filepath.Join(pkg.dir, "data", group)
+		if !exists(root) {
+			continue
+		}
+		err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+			if err != nil || fi.IsDir() || filepath.Ext(path) != ".in" {
+				return err
+			}
+			stem := strings.TrimSuffix(path, ".in")
+			input, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			answer, err := ioutil.ReadFile(stem + ".ans")
+			if err != nil {
+				return err
+			}
+			name, _ := filepath.Rel(filepath.Join(pkg.dir, "data"), stem)
+			cases = append(cases, testCase{Name: name, Input: string(input), Answer: string(answer)})
+			return nil
+		})
+		if err != nil {
+			panic(err)
+		}
+	}
+	if len(cases) == 0 {
+		panic(fmt.Sprintf("no test case in %s", filepath.Join(pkg.dir, "data")))
+	}
+	return cases
+}
+
+// config applies the limits and validator flags of the package to the
+// settings of the problem in config. Like the default validator of Kattis,
+// case and spacing are ignored unless the flags say otherwise.
+func (pkg problemPackage) config(prob problemConfig) (problemConfig, bool) {
+	byteByByte := false
+	prob.ignoreCase, prob.ignoreSpace = true, true
+	if pkg.Limits.TimeLimit > 0 {
+		prob.TimeLimit = strconv.FormatFloat(pkg.Limits.TimeLimit, 'g', -1, 64) + "s"
+	}
+	flags := strings.Fields(pkg.ValidatorFlags)
+	for i, flag := range flags {
+		switch flag {
+		case "float_tolerance", "float_absolute_tolerance", "float_relative_tolerance":
+			if i+1 < len(flags) {
+				if t, err := strconv.ParseFloat(flags[i+1], 64); err == nil {
+					prob.Tolerance = t
+				}
+			}
+		case "case_sensitive":
+			prob.ignoreCase = false
+		case "space_change_sensitive":
+			byteByByte, prob.ignoreSpace = true, false
+		}
+	}
+	return prob, byteByByte
+}
+
+// validator compiles the output validator of the package in dir, and returns
+// the command to run it, or nil if the package has none.
+func (pkg problemPackage) validator(dir string) []string {
+	root := filepath.Join(pkg.dir, "output_validators")
+	entries, err := ioutil.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		panic(err)
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		root = filepath.Join(root, entries[0].Name())
+		if entries, err = ioutil.ReadDir(root); err != nil {
+			panic(err)
+		}
+	}
+
+	// copy it all, as the validator may include headers like testlib.h
+	var sources []string
+	for _, fi := range entries {
+		if fi.IsDir() {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(root, fi.Name()))
+		if err != nil {
+			panic(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, fi.Name()), data, fi.Mode()); err != nil {
+			panic(err)
+		}
+		if _, ok := config.Test[strings.TrimPrefix(filepath.Ext(fi.Name()), ".")]; ok {
+			sources = append(sources, fi.Name())
+		}
+	}
+	if len(sources) != 1 {
+		panic(fmt.Sprintf("%s: expected one source file of a language in config, found %d", root, len(sources)))
+	}
+
+	file := filepath.Join(dir, sources[0])
+	test := config.Test[strings.TrimPrefix(filepath.Ext(file), ".")]
+	vars := newCmdVars(file, 0)
+	stop := spin("Compiling the output validator")
+	out, failed := compileSource(test, vars, dir)
+	stop()
+	if failed {
+		panic("the output validator does not compile:\n" + out)
+	}
+
+	// the checker runs in the current directory, and its arguments are
+	// placeholders, so make paths absolute and escape braces
+	run := renderCmd(test.Run, vars)
+	escape := strings.NewReplacer("{", "{{", "}", "}}")
+	var cmd []string
+	for i, arg := range run.Args {
+		if i == 0 && !filepath.IsAbs(arg) && strings.ContainsRune(arg, filepath.Separator) {
+			arg = filepath.Join(dir, arg)
+		}
+		cmd = append(cmd, escape.Replace(arg))
+	}
+	cmd = append(cmd, "{input}", "{answer}", "{feedback}")
+	for _, flag := range strings.Fields(pkg.ValidatorFlags) {
+		cmd = append(cmd, escape.Replace(flag))
+	}
+	return cmd
+}
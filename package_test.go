@@ -0,0 +1,107 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeFiles creates files under dir from their slash-separated paths.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, data := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadPackage(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"problem.yaml":                   "name: Hello\nlimits:\n  time_limit: 2.5\n  memory: 1024\nvalidator_flags: float_tolerance 1e-6\n",
+		"data/sample/1.in":               "1\n",
+		"data/sample/1.ans":              "one\n",
+		"data/secret/group1/02-big.in":   "2\n",
+		"data/secret/group1/02-big.ans":  "two\n",
+		"data/secret/01-small.in":        "3\n",
+		"data/secret/01-small.ans":       "three\n",
+		"data/secret/no-answer.in.txt":   "ignored\n",
+		"output_validators/README.md":    "ignored\n",
+		"problem_statement/problem.tex":  "ignored\n",
+		"submissions/accepted/hello.cpp": "ignored\n",
+	})
+	pkg := readPackage(dir)
+	if pkg.Name != "Hello" || pkg.Limits.TimeLimit != 2.5 || pkg.ValidatorFlags != "float_tolerance 1e-6" {
+		t.Errorf("readPackage = %+v", pkg)
+	}
+	want := []testCase{
+		{"sample/1", "1\n", "one\n"},
+		{"secret/01-small", "3\n", "three\n"},
+		{"secret/group1/02-big", "2\n", "two\n"},
+	}
+	if cases := pkg.cases(); !reflect.DeepEqual(cases, want) {
+		t.Errorf("cases = %q, want %q", cases, want)
+	}
+}
+
+func TestReadPackageTimelimitFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"problem.yaml": "name: Old\n",
+		".timelimit":   "3\n",
+	})
+	if pkg := readPackage(dir); pkg.Limits.TimeLimit != 3 {
+		t.Errorf("time limit = %g, want 3 from .timelimit", pkg.Limits.TimeLimit)
+	}
+}
+
+func TestPackageConfig(t *testing.T) {
+	tests := []struct {
+		flags                   string
+		tolerance               float64
+		ignoreCase, ignoreSpace bool
+		byteByByte              bool
+	}{
+		{"", 0, true, true, false},
+		{"float_tolerance 1e-6", 1e-6, true, true, false},
+		{"float_absolute_tolerance 0.01 case_sensitive", 0.01, false, true, false},
+		{"space_change_sensitive float_relative_tolerance 1e-9", 1e-9, true, false, true},
+		{"case_sensitive space_change_sensitive", 0, false, false, true},
+		{"float_tolerance", 0, true, true, false},
+	}
+	for _, test := range tests {
+		pkg := problemPackage{ValidatorFlags: test.flags}
+		pkg.Limits.TimeLimit = 1.5
+		prob, byteByByte := pkg.config(problemConfig{TimeLimit: "3s"})
+		if prob.TimeLimit != "1.5s" || prob.Tolerance != test.tolerance || byteByByte != test.byteByByte ||
+			prob.ignoreCase != test.ignoreCase || prob.ignoreSpace != test.ignoreSpace {
+			t.Errorf("%q: got %+v, %v", test.flags, prob, byteByByte)
+		}
+	}
+}
+
+func TestCompareOutputLikeKattis(t *testing.T) {
+	tests := []struct {
+		output, answer          string
+		ignoreCase, ignoreSpace bool
+		verdict                 string
+	}{
+		{"yes\n", "YES\n", true, true, accepted},
+		{"yes\n", "YES\n", false, true, wrongAnswer},
+		{"1  2\n\n", "1 2\n", true, true, accepted},
+		{"1\n2\n", "1 2\n", true, false, presentationError},
+		{"1 3\n", "1 2\n", true, true, wrongAnswer},
+	}
+	for _, test := range tests {
+		prob := problemConfig{ignoreCase: test.ignoreCase, ignoreSpace: test.ignoreSpace}
+		if verdict, _ := compareOutput(prob, nil, "", test.output, test.answer, false); verdict != test.verdict {
+			t.Errorf("%q for %q: got %s, want %s", test.output, test.answer, verdict, test.verdict)
+		}
+	}
+}
@@ -0,0 +1,1191 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// A small PDF reader that extracts the text of problem descriptions without
+// pdftotext. It handles what the UVa PDFs use: Flate compressed streams,
+// object streams, simple fonts with Differences encodings or built-in Type1
+// encodings, ToUnicode CMaps and form XObjects. Text is emitted in content
+// stream order, starting a new line whenever the baseline moves.
+
+type (
+	pdfName    string
+	pdfString  string
+	pdfKeyword string
+	pdfArray   []interface{}
+	pdfDict    map[pdfName]interface{}
+	pdfRef     struct{ num, gen int }
+	pdfStream  struct {
+		dict pdfDict
+		raw  []byte
+	}
+)
+
+// pdfError is panicked inside the reader and recovered by readPdfText.
+type pdfError string
+
+func pdfFail(format string, a ...interface{}) {
+	panic(pdfError(fmt.Sprintf(format, a...)))
+}
+
+// pdfTry runs fn and reports whether it finished. Damaged objects and
+// streams are skipped this way, so they do not fail the whole document.
+func pdfTry(fn func()) (ok bool) {
+	defer func() {
+		if e := recover(); e != nil {
+			ok = false
+		}
+	}()
+	fn()
+	return true
+}
+
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func isPdfSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPdfDelim(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		} else if isPdfSpace(c) {
+			l.pos++
+		} else {
+			return
+		}
+	}
+}
+
+// next returns the next token: a float64, pdfName, pdfString or pdfKeyword.
+// Delimiters of arrays and dictionaries are returned as keywords.
+// It returns nil at the end of data.
+func (l *pdfLexer) next() interface{} {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil
+	}
+	start := l.pos
+	c := l.data[l.pos]
+	switch {
+	case c == '(':
+		return l.literalString()
+	case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+		l.pos += 2
+		return pdfKeyword("<<")
+	case c == '>' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '>':
+		l.pos += 2
+		return pdfKeyword(">>")
+	case c == '<':
+		end := bytes.IndexByte(l.data[l.pos:], '>')
+		if end < 0 {
+			pdfFail("unterminated hex string")
+		}
+		l.pos += end + 1
+		return hexString(l.data[start+1 : start+end])
+	case c == '[' || c == ']' || c == '{' || c == '}':
+		l.pos++
+		return pdfKeyword(l.data[start:l.pos])
+	case c == '/':
+		l.pos++
+		for l.pos < len(l.data) && !isPdfSpace(l.data[l.pos]) && !isPdfDelim(l.data[l.pos]) {
+			l.pos++
+		}
+		return pdfName(unescapeName(string(l.data[start+1 : l.pos])))
+	case c == ')' || c == '>':
+		pdfFail("unexpected %q at offset %d", c, l.pos)
+	}
+	for l.pos < len(l.data) && !isPdfSpace(l.data[l.pos]) && !isPdfDelim(l.data[l.pos]) {
+		l.pos++
+	}
+	word := string(l.data[start:l.pos])
+	if strings.IndexAny(word[:1], "+-.0123456789") == 0 {
+		if f, err := strconv.ParseFloat(word, 64); err == nil {
+			return f
+		}
+	}
+	return pdfKeyword(word)
+}
+
+func (l *pdfLexer) literalString() pdfString {
+	var buf []byte
+	depth := 0
+	for l.pos++; l.pos < len(l.data); l.pos++ {
+		c := l.data[l.pos]
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				l.pos++
+				return pdfString(buf)
+			}
+			depth--
+		case '\\':
+			l.pos++
+			if l.pos >= len(l.data) {
+				break
+			}
+			c = l.data[l.pos]
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				// line continuation
+				if l.pos+1 < len(l.data) && l.data[l.pos+1] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if '0' <= c && c <= '7' {
+					n := 0
+					for i := 0; i < 3 && l.pos < len(l.data) && '0' <= l.data[l.pos] && l.data[l.pos] <= '7'; i++ {
+						n = n*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					l.pos--
+					c = byte(n)
+				}
+			}
+		}
+		buf = append(buf, c)
+	}
+	pdfFail("unterminated string")
+	return ""
+}
+
+func hexString(s []byte) pdfString {
+	digits := make([]byte, 0, len(s)+1)
+	for _, c := range s {
+		if !isPdfSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	b := make([]byte, len(digits)/2)
+	if _, err := hex.Decode(b, digits); err != nil {
+		pdfFail("bad hex string: %s", err)
+	}
+	return pdfString(b)
+}
+
+func unescapeName(s string) string {
+	if !strings.Contains(s, "#") {
+		return s
+	}
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '#' && i+2 < len(s) {
+			if b, err := strconv.ParseUint(s[i+1:i+3], 16, 8); err == nil {
+				buf.WriteByte(byte(b))
+				i += 2
+				continue
+			}
+		}
+		buf.WriteByte(s[i])
+	}
+	return buf.String()
+}
+
+// object parses an object starting with tok, which has been read already.
+func (l *pdfLexer) object(tok interface{}) interface{} {
+	switch t := tok.(type) {
+	case pdfKeyword:
+		switch t {
+		case "[":
+			var arr pdfArray
+			for {
+				tok := l.next()
+				if tok == pdfKeyword("]") {
+					return arr
+				} else if tok == nil {
+					pdfFail("unterminated array")
+				}
+				arr = append(arr, l.object(tok))
+			}
+		case "<<":
+			dict := make(pdfDict)
+			for {
+				tok := l.next()
+				if tok == pdfKeyword(">>") {
+					return dict
+				}
+				key, ok := tok.(pdfName)
+				if !ok {
+					pdfFail("dictionary key is not a name at offset %d", l.pos)
+				}
+				dict[key] = l.object(l.next())
+			}
+		case "null":
+			return nil
+		case "true":
+			return true
+		case "false":
+			return false
+		}
+	case float64:
+		// try "num gen R"
+		save := l.pos
+		if gen, ok := l.next().(float64); ok {
+			if l.next() == pdfKeyword("R") {
+				return pdfRef{int(t), int(gen)}
+			}
+		}
+		l.pos = save
+	}
+	return tok
+}
+
+type pdfFile struct {
+	data []byte
+	// byte offsets of "N G obj"
+	offsets map[int]int
+	// object number => number of the object stream containing it
+	inObjStm map[int]int
+	objStms  map[int]pdfObjStm
+	cache    map[int]interface{}
+}
+
+type pdfObjStm struct {
+	data []byte
+	// object number => offset in data
+	offsets map[int]int
+}
+
+var pdfObjRegex = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+func newPdfFile(data []byte) *pdfFile {
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		pdfFail("not a PDF file")
+	}
+	f := &pdfFile{
+		data:     data,
+		offsets:  make(map[int]int),
+		inObjStm: make(map[int]int),
+		objStms:  make(map[int]pdfObjStm),
+		cache:    make(map[int]interface{}),
+	}
+	// Scanning the objects instead of reading the xref table tolerates
+	// broken offsets. Later definitions override earlier ones.
+	for _, m := range pdfObjRegex.FindAllSubmatchIndex(data, -1) {
+		num, _ := strconv.Atoi(string(data[m[2]:m[3]]))
+		f.offsets[num] = m[0]
+	}
+	for num := range f.offsets {
+		if s, ok := f.object(num).(pdfStream); ok && s.dict["Type"] == pdfName("ObjStm") {
+			var stm pdfObjStm
+			if !pdfTry(func() { stm = f.parseObjStm(s) }) {
+				continue
+			}
+			f.objStms[num] = stm
+			for n := range stm.offsets {
+				if _, ok := f.offsets[n]; !ok {
+					f.inObjStm[n] = num
+				}
+			}
+		}
+	}
+	// forget references resolved before their object streams were known
+	for num, obj := range f.cache {
+		if obj == nil {
+			delete(f.cache, num)
+		}
+	}
+	return f
+}
+
+func (f *pdfFile) parseObjStm(s pdfStream) pdfObjStm {
+	stm := pdfObjStm{data: f.decode(s), offsets: make(map[int]int)}
+	first := int(f.number(s.dict["First"]))
+	l := &pdfLexer{data: stm.data}
+	for i := 0; i < int(f.number(s.dict["N"])); i++ {
+		num, ok1 := l.next().(float64)
+		off, ok2 := l.next().(float64)
+		if !ok1 || !ok2 {
+			pdfFail("bad object stream header")
+		}
+		stm.offsets[int(num)] = first + int(off)
+	}
+	return stm
+}
+
+func (f *pdfFile) object(num int) interface{} {
+	if obj, ok := f.cache[num]; ok {
+		return obj
+	}
+	// guard against reference cycles
+	f.cache[num] = nil
+	var obj interface{}
+	ok := pdfTry(func() {
+		if off, ok := f.offsets[num]; ok {
+			l := &pdfLexer{data: f.data, pos: off}
+			l.next()
+			l.next()
+			if l.next() != pdfKeyword("obj") {
+				pdfFail("bad object %d", num)
+			}
+			obj = l.object(l.next())
+			if dict, ok := obj.(pdfDict); ok {
+				save := l.pos
+				if l.next() == pdfKeyword("stream") {
+					obj = f.streamData(l, dict)
+				} else {
+					l.pos = save
+				}
+			}
+		} else if n, ok := f.inObjStm[num]; ok {
+			stm := f.objStms[n]
+			l := &pdfLexer{data: stm.data, pos: stm.offsets[num]}
+			obj = l.object(l.next())
+		}
+	})
+	// an unreadable object is null, like a missing one
+	if !ok {
+		obj = nil
+	}
+	f.cache[num] = obj
+	return obj
+}
+
+func (f *pdfFile) streamData(l *pdfLexer, dict pdfDict) pdfStream {
+	// the keyword is followed by CRLF or LF
+	if l.pos < len(l.data) && l.data[l.pos] == '\r' {
+		l.pos++
+	}
+	if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+		l.pos++
+	}
+	start := l.pos
+	length := int(f.number(dict["Length"]))
+	end := start + length
+	if length <= 0 || end > len(l.data) ||
+		!bytes.HasPrefix(bytes.TrimLeft(l.data[end:], "\r\n "), []byte("endstream")) {
+		// a wrong Length is common, look for the keyword instead
+		i := bytes.Index(l.data[start:], []byte("endstream"))
+		if i < 0 {
+			pdfFail("unterminated stream")
+		}
+		end = start + i
+	}
+	return pdfStream{dict, l.data[start:end]}
+}
+
+func (f *pdfFile) resolve(v interface{}) interface{} {
+	for i := 0; i < 32; i++ {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = f.object(ref.num)
+	}
+	return nil
+}
+
+func (f *pdfFile) dict(v interface{}) pdfDict {
+	switch d := f.resolve(v).(type) {
+	case pdfDict:
+		return d
+	case pdfStream:
+		return d.dict
+	}
+	return nil
+}
+
+func (f *pdfFile) array(v interface{}) pdfArray {
+	a, _ := f.resolve(v).(pdfArray)
+	return a
+}
+
+func (f *pdfFile) number(v interface{}) float64 {
+	n, _ := f.resolve(v).(float64)
+	return n
+}
+
+// decode returns the decoded data of a stream.
+func (f *pdfFile) decode(s pdfStream) []byte {
+	data := s.raw
+	var filters []interface{}
+	switch filter := f.resolve(s.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []interface{}{filter}
+	case pdfArray:
+		filters = filter
+	}
+	for _, filter := range filters {
+		switch f.resolve(filter) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			r, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				pdfFail("bad flate stream: %s", err)
+			}
+			// keep what was decoded before a checksum error
+			out, err := ioutil.ReadAll(r)
+			if err != nil && len(out) == 0 {
+				pdfFail("bad flate stream: %s", err)
+			}
+			data = out
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			src := bytes.TrimSpace(data)
+			src = bytes.TrimPrefix(src, []byte("<~"))
+			if i := bytes.Index(src, []byte("~>")); i >= 0 {
+				src = src[:i]
+			}
+			out := make([]byte, 4*len(src)/5+4)
+			n, _, err := ascii85.Decode(out, src, true)
+			if err != nil {
+				pdfFail("bad ascii85 stream: %s", err)
+			}
+			data = out[:n]
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			if i := bytes.IndexByte(data, '>'); i >= 0 {
+				data = data[:i]
+			}
+			data = []byte(hexString(data))
+		default:
+			pdfFail("unsupported filter %v", filter)
+		}
+	}
+	return data
+}
+
+func (f *pdfFile) catalog() pdfDict {
+	if i := bytes.LastIndex(f.data, []byte("trailer")); i >= 0 {
+		l := &pdfLexer{data: f.data, pos: i + len("trailer")}
+		if trailer, ok := l.object(l.next()).(pdfDict); ok {
+			if root := f.dict(trailer["Root"]); root != nil {
+				return root
+			}
+		}
+	}
+	// PDF 1.5 files may keep the trailer in a cross-reference stream
+	nums := make([]int, 0, len(f.offsets)+len(f.inObjStm))
+	for num := range f.offsets {
+		nums = append(nums, num)
+	}
+	for num := range f.inObjStm {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		if d := f.dict(pdfRef{num, 0}); d["Type"] == pdfName("Catalog") {
+			return d
+		}
+	}
+	pdfFail("no document catalog")
+	return nil
+}
+
+type pdfPage struct {
+	contents  []byte
+	resources pdfDict
+}
+
+func (f *pdfFile) pages(node pdfDict, resources pdfDict, depth int, out []pdfPage) []pdfPage {
+	if depth > 64 {
+		pdfFail("page tree too deep")
+	}
+	if r := f.dict(node["Resources"]); r != nil {
+		resources = r
+	}
+	if node["Type"] == pdfName("Page") || node["Kids"] == nil {
+		var contents []byte
+		var streams []interface{}
+		switch c := f.resolve(node["Contents"]).(type) {
+		case pdfStream:
+			streams = []interface{}{c}
+		case pdfArray:
+			streams = c
+		}
+		for _, s := range streams {
+			if s, ok := f.resolve(s).(pdfStream); ok {
+				pdfTry(func() { contents = append(contents, f.decode(s)...) })
+				contents = append(contents, '\n')
+			}
+		}
+		return append(out, pdfPage{contents, resources})
+	}
+	for _, kid := range f.array(node["Kids"]) {
+		if d := f.dict(kid); d != nil {
+			out = f.pages(d, resources, depth+1, out)
+		}
+	}
+	return out
+}
+
+// readPdfText extracts the text of a PDF, with pages separated by form
+// feeds like pdftotext.
+func readPdfText(data []byte) (text string, err error) {
+	defer func() {
+		// malformed input may also cause runtime errors
+		if e := recover(); e != nil {
+			err = fmt.Errorf("%v", e)
+		}
+	}()
+	f := newPdfFile(data)
+	if _, encrypted := f.resolveTrailerKey("Encrypt"); encrypted {
+		pdfFail("encrypted PDF files are not supported")
+	}
+	root := f.catalog()
+	var buf strings.Builder
+	for _, page := range f.pages(f.dict(root["Pages"]), nil, 0, nil) {
+		// a page that fails midway keeps the text shown so far
+		t := newPdfTextWriter(f, &buf)
+		pdfTry(func() { t.run(page.contents, page.resources, 0) })
+		buf.WriteString("\n\f")
+	}
+	return buf.String(), nil
+}
+
+func (f *pdfFile) resolveTrailerKey(key pdfName) (interface{}, bool) {
+	if i := bytes.LastIndex(f.data, []byte("trailer")); i >= 0 {
+		l := &pdfLexer{data: f.data, pos: i + len("trailer")}
+		if trailer, ok := l.object(l.next()).(pdfDict); ok {
+			v, ok := trailer[key]
+			return v, ok
+		}
+	}
+	for num := range f.offsets {
+		if s, ok := f.object(num).(pdfStream); ok && s.dict["Type"] == pdfName("XRef") {
+			if v, ok := s.dict[key]; ok {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// pdfFont converts character codes of a font to text.
+type pdfFont struct {
+	// ToUnicode CMap, keyed by the bytes of the code
+	cmap     map[string]string
+	codeLens []int
+	// code => text for simple fonts without a CMap
+	encoding map[int]string
+	widths   map[int]float64
+	defWidth float64
+	// composite fonts use 2-byte codes
+	composite bool
+}
+
+type pdfGlyph struct {
+	code  int
+	text  string
+	width float64
+	// single byte code 32, which gets the word spacing
+	space bool
+}
+
+func (f *pdfFile) loadFont(v interface{}) *pdfFont {
+	d := f.dict(v)
+	font := &pdfFont{defWidth: 500, widths: make(map[int]float64)}
+	if d == nil {
+		return font
+	}
+	font.composite = d["Subtype"] == pdfName("Type0")
+	if s, ok := f.resolve(d["ToUnicode"]).(pdfStream); ok {
+		font.cmap, font.codeLens = parseCMap(f.decode(s))
+	}
+
+	if font.composite {
+		font.defWidth = 1000
+		if arr := f.array(d["DescendantFonts"]); len(arr) > 0 {
+			desc := f.dict(arr[0])
+			if dw, ok := f.resolve(desc["DW"]).(float64); ok {
+				font.defWidth = dw
+			}
+			w := f.array(desc["W"])
+			for i := 0; i+1 < len(w); {
+				first := int(f.number(w[i]))
+				if ws, ok := f.resolve(w[i+1]).(pdfArray); ok {
+					for j, x := range ws {
+						font.widths[first+j] = f.number(x)
+					}
+					i += 2
+				} else if i+2 < len(w) {
+					last := int(f.number(w[i+1]))
+					for c := first; c <= last && c-first < 65536; c++ {
+						font.widths[c] = f.number(w[i+2])
+					}
+					i += 3
+				} else {
+					break
+				}
+			}
+		}
+		return font
+	}
+
+	first := int(f.number(d["FirstChar"]))
+	for i, w := range f.array(d["Widths"]) {
+		font.widths[first+i] = f.number(w)
+	}
+	if desc := f.dict(d["FontDescriptor"]); desc != nil {
+		if mw, ok := f.resolve(desc["MissingWidth"]).(float64); ok && mw > 0 {
+			font.defWidth = mw
+		}
+	}
+
+	font.encoding = make(map[int]string)
+	// built-in encoding of an embedded Type1 font, then the base encoding,
+	// then the differences
+	if desc := f.dict(d["FontDescriptor"]); desc != nil {
+		if s, ok := f.resolve(desc["FontFile"]).(pdfStream); ok {
+			for code, name := range type1Encoding(f.decode(s)) {
+				if text, ok := glyphText(name); ok {
+					font.encoding[code] = text
+				}
+			}
+		}
+	}
+	var differences pdfArray
+	switch enc := f.resolve(d["Encoding"]).(type) {
+	case pdfName:
+		setBaseEncoding(font.encoding, enc)
+	case pdfDict:
+		if base, ok := f.resolve(enc["BaseEncoding"]).(pdfName); ok {
+			setBaseEncoding(font.encoding, base)
+		}
+		differences = f.array(enc["Differences"])
+	}
+	code := 0
+	for _, v := range differences {
+		switch v := f.resolve(v).(type) {
+		case float64:
+			code = int(v)
+		case pdfName:
+			if text, ok := glyphText(string(v)); ok {
+				font.encoding[code] = text
+			}
+			code++
+		}
+	}
+	return font
+}
+
+func setBaseEncoding(encoding map[int]string, name pdfName) {
+	if name != "WinAnsiEncoding" && name != "StandardEncoding" && name != "MacRomanEncoding" {
+		return
+	}
+	for code := 32; code < 256; code++ {
+		if name == "WinAnsiEncoding" && 128 <= code && code < 160 {
+			if text, ok := cp1252[code]; ok {
+				encoding[code] = text
+			}
+		} else if code < 127 || code >= 160 && name == "WinAnsiEncoding" {
+			encoding[code] = string(rune(code))
+		}
+	}
+	if name == "StandardEncoding" {
+		encoding[0x27] = "’"
+		encoding[0x60] = "‘"
+	}
+}
+
+var cp1252 = map[int]string{
+	0x80: "€", 0x85: "…", 0x91: "‘", 0x92: "’", 0x93: "“", 0x94: "”",
+	0x95: "•", 0x96: "–", 0x97: "—", 0x99: "™",
+}
+
+var type1EncodingRegex = regexp.MustCompile(`dup\s+(\d+)\s*/([^\s/]+)\s+put`)
+
+// type1Encoding reads the built-in encoding of a Type1 font program, which
+// is in the clear text part before eexec.
+func type1Encoding(program []byte) map[int]string {
+	if i := bytes.Index(program, []byte("eexec")); i >= 0 {
+		program = program[:i]
+	}
+	encoding := make(map[int]string)
+	for _, m := range type1EncodingRegex.FindAllSubmatch(program, -1) {
+		code, _ := strconv.Atoi(string(m[1]))
+		encoding[code] = string(m[2])
+	}
+	return encoding
+}
+
+var glyphNames = map[string]string{
+	"space": " ", "exclam": "!", "quotedbl": "\"", "numbersign": "#", "dollar": "$",
+	"percent": "%", "ampersand": "&", "quoteright": "’", "quotesingle": "'",
+	"parenleft": "(", "parenright": ")", "asterisk": "*", "plus": "+", "comma": ",",
+	"hyphen": "-", "period": ".", "slash": "/", "colon": ":", "semicolon": ";",
+	"less": "<", "equal": "=", "greater": ">", "question": "?", "at": "@",
+	"bracketleft": "[", "backslash": "\\", "bracketright": "]", "asciicircum": "^",
+	"underscore": "_", "quoteleft": "‘", "grave": "`", "braceleft": "{", "bar": "|",
+	"braceright": "}", "asciitilde": "~", "zero": "0", "one": "1", "two": "2",
+	"three": "3", "four": "4", "five": "5", "six": "6", "seven": "7", "eight": "8",
+	"nine": "9", "endash": "–", "emdash": "—", "quotedblleft": "“", "quotedblright": "”",
+	"quotesinglbase": "‚", "quotedblbase": "„", "ellipsis": "…", "bullet": "•",
+	"fi": "fi", "fl": "fl", "ff": "ff", "ffi": "ffi", "ffl": "ffl", "dotlessi": "ı",
+	"minus": "−", "multiply": "×", "divide": "÷", "periodcentered": "·",
+	"degree": "°", "section": "§", "paragraph": "¶", "copyright": "©",
+	"registered": "®", "trademark": "™", "plusminus": "±", "lessequal": "≤",
+	"greaterequal": "≥", "notequal": "≠", "infinity": "∞", "summation": "∑",
+	"product": "∏", "radical": "√", "arrowright": "→", "arrowleft": "←",
+	"logicaland": "∧", "logicalor": "∨", "element": "∈", "similar": "∼",
+	"approxequal": "≈", "equivalence": "≡", "floorleft": "⌊", "floorright": "⌋",
+	"ceilingleft": "⌈", "ceilingright": "⌉", "angbracketleft": "⟨", "angbracketright": "⟩",
+	"asteriskmath": "∗", "dieresis": "¨", "acute": "´", "circumflex": "ˆ", "tilde": "˜",
+	"germandbls": "ß", "ae": "æ", "oe": "œ", "oslash": "ø", "AE": "Æ", "OE": "Œ", "Oslash": "Ø",
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ε", "lambda": "λ",
+	"mu": "μ", "pi": "π", "sigma": "σ", "theta": "θ", "omega": "ω", "Delta": "∆", "Omega": "Ω",
+	"visiblespace": "␣", "nbspace": " ", "sfthyphen": "-",
+}
+
+var accented = map[string]string{
+	"acute": "́", "grave": "̀", "circumflex": "̂", "tilde": "̃",
+	"dieresis": "̈", "cedilla": "̧", "ring": "̊", "caron": "̌",
+}
+
+// glyphText maps a glyph name to text, following the Adobe glyph list
+// conventions for the common names.
+func glyphText(name string) (string, bool) {
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		// variants like a.sc
+		name = name[:i]
+	}
+	if text, ok := glyphNames[name]; ok {
+		return text, true
+	}
+	if len(name) == 1 {
+		return name, true
+	}
+	if strings.HasPrefix(name, "uni") && len(name) == 7 {
+		if r, err := strconv.ParseUint(name[3:], 16, 32); err == nil {
+			return string(rune(r)), true
+		}
+	}
+	if strings.HasPrefix(name, "u") && len(name) >= 5 && len(name) <= 7 {
+		if r, err := strconv.ParseUint(name[1:], 16, 32); err == nil {
+			return string(rune(r)), true
+		}
+	}
+	// composed Latin letters like eacute
+	for suffix, mark := range accented {
+		if len(name) == len(suffix)+1 && strings.HasSuffix(name, suffix) {
+			return name[:1] + mark, true
+		}
+	}
+	return "", false
+}
+
+// parseCMap reads the bfchar and bfrange mappings of a ToUnicode CMap.
+func parseCMap(data []byte) (cmap map[string]string, codeLens []int) {
+	cmap = make(map[string]string)
+	lens := make(map[int]bool)
+	l := &pdfLexer{data: data}
+	var operands []interface{}
+	utf16Text := func(s pdfString) string {
+		b := []byte(s)
+		u := make([]uint16, len(b)/2)
+		for i := range u {
+			u[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+		}
+		return string(utf16.Decode(u))
+	}
+	for {
+		tok := l.next()
+		if tok == nil {
+			break
+		}
+		kw, ok := tok.(pdfKeyword)
+		if !ok || kw == "[" {
+			operands = append(operands, l.object(tok))
+			continue
+		}
+		switch kw {
+		case "endcodespacerange":
+			for _, v := range operands {
+				if s, ok := v.(pdfString); ok && len(s) > 0 {
+					lens[len(s)] = true
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(pdfString)
+				dst, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 {
+					cmap[string(src)] = utf16Text(dst)
+					lens[len(src)] = true
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if !ok1 || !ok2 || len(lo) != len(hi) || len(lo) == 0 {
+					continue
+				}
+				lens[len(lo)] = true
+				start, end := codeOf(lo), codeOf(hi)
+				for c := start; c <= end && c-start < 65536; c++ {
+					src := codeBytes(c, len(lo))
+					switch dst := operands[i+2].(type) {
+					case pdfString:
+						if len(dst) < 2 {
+							continue
+						}
+						// increment the last UTF-16 unit
+						b := []byte(dst)
+						last := int(b[len(b)-2])<<8 | int(b[len(b)-1])
+						last += c - start
+						b[len(b)-2], b[len(b)-1] = byte(last>>8), byte(last)
+						cmap[src] = utf16Text(pdfString(b))
+					case pdfArray:
+						if c-start < len(dst) {
+							if s, ok := dst[c-start].(pdfString); ok {
+								cmap[src] = utf16Text(s)
+							}
+						}
+					}
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+	for n := range lens {
+		codeLens = append(codeLens, n)
+	}
+	sort.Ints(codeLens)
+	return
+}
+
+func codeOf(s pdfString) int {
+	c := 0
+	for i := 0; i < len(s); i++ {
+		c = c<<8 | int(s[i])
+	}
+	return c
+}
+
+func codeBytes(c, n int) string {
+	b := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		b[i] = byte(c)
+		c >>= 8
+	}
+	return string(b)
+}
+
+func (font *pdfFont) glyphs(s pdfString) []pdfGlyph {
+	var glyphs []pdfGlyph
+	minLen := 1
+	if font.composite {
+		minLen = 2
+	}
+	for i := 0; i < len(s); {
+		n, text, found := minLen, "", false
+		if font.cmap != nil {
+			for _, l := range font.codeLens {
+				if i+l <= len(s) {
+					if t, ok := font.cmap[string(s[i:i+l])]; ok {
+						n, text, found = l, t, true
+						break
+					}
+				}
+			}
+		}
+		if i+n > len(s) {
+			n = len(s) - i
+		}
+		code := codeOf(s[i : i+n])
+		if !found && !font.composite {
+			if t, ok := font.encoding[code]; ok {
+				text = t
+			} else if 32 <= code && code < 127 {
+				text = string(rune(code))
+			}
+		}
+		w, ok := font.widths[code]
+		if !ok {
+			w = font.defWidth
+		}
+		glyphs = append(glyphs, pdfGlyph{code, text, w, n == 1 && code == 32})
+		i += n
+	}
+	return glyphs
+}
+
+type pdfMatrix [6]float64
+
+var identity = pdfMatrix{1, 0, 0, 1, 0, 0}
+
+func (m pdfMatrix) mul(n pdfMatrix) pdfMatrix {
+	return pdfMatrix{
+		m[0]*n[0] + m[1]*n[2], m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2], m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4], m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+type pdfGraphicsState struct {
+	ctm                                  pdfMatrix
+	font                                 *pdfFont
+	fontSize, charSpace, wordSpace, rise float64
+	hScale, leading                      float64
+}
+
+type pdfTextWriter struct {
+	f     *pdfFile
+	buf   *strings.Builder
+	fonts map[interface{}]*pdfFont
+	pdfGraphicsState
+	stack    []pdfGraphicsState
+	tm, tlm  pdfMatrix
+	started  bool
+	lastX    float64
+	lastY    float64
+	lastSize float64
+}
+
+func newPdfTextWriter(f *pdfFile, buf *strings.Builder) *pdfTextWriter {
+	return &pdfTextWriter{
+		f:                f,
+		buf:              buf,
+		fonts:            make(map[interface{}]*pdfFont),
+		pdfGraphicsState: pdfGraphicsState{ctm: identity, hScale: 1, font: &pdfFont{defWidth: 500}},
+	}
+}
+
+func (t *pdfTextWriter) run(contents []byte, resources pdfDict, depth int) {
+	if depth > 8 {
+		return
+	}
+	l := &pdfLexer{data: contents}
+	var operands []interface{}
+	num := func(i int) float64 {
+		if i < len(operands) {
+			if n, ok := operands[i].(float64); ok {
+				return n
+			}
+		}
+		return 0
+	}
+	for {
+		tok := l.next()
+		if tok == nil {
+			return
+		}
+		op, ok := tok.(pdfKeyword)
+		if !ok || op == "[" || op == "<<" {
+			operands = append(operands, l.object(tok))
+			continue
+		}
+		switch op {
+		case "q":
+			t.stack = append(t.stack, t.pdfGraphicsState)
+		case "Q":
+			if n := len(t.stack); n > 0 {
+				t.pdfGraphicsState = t.stack[n-1]
+				t.stack = t.stack[:n-1]
+			}
+		case "cm":
+			if len(operands) == 6 {
+				t.ctm = pdfMatrix{num(0), num(1), num(2), num(3), num(4), num(5)}.mul(t.ctm)
+			}
+		case "BT":
+			t.tm, t.tlm = identity, identity
+		case "Tf":
+			if len(operands) == 2 {
+				t.font = t.loadFont(resources, operands[0])
+				t.fontSize = num(1)
+			}
+		case "Tc":
+			t.charSpace = num(0)
+		case "Tw":
+			t.wordSpace = num(0)
+		case "Tz":
+			t.hScale = num(0) / 100
+		case "TL":
+			t.leading = num(0)
+		case "Ts":
+			t.rise = num(0)
+		case "Td":
+			t.moveLine(num(0), num(1))
+		case "TD":
+			t.leading = -num(1)
+			t.moveLine(num(0), num(1))
+		case "Tm":
+			if len(operands) == 6 {
+				t.tm = pdfMatrix{num(0), num(1), num(2), num(3), num(4), num(5)}
+				t.tlm = t.tm
+			}
+		case "T*":
+			t.moveLine(0, -t.leading)
+		case "Tj":
+			if len(operands) > 0 {
+				t.show(operands[0])
+			}
+		case "'":
+			t.moveLine(0, -t.leading)
+			if len(operands) > 0 {
+				t.show(operands[0])
+			}
+		case "\"":
+			if len(operands) == 3 {
+				t.wordSpace, t.charSpace = num(0), num(1)
+				t.moveLine(0, -t.leading)
+				t.show(operands[2])
+			}
+		case "TJ":
+			if len(operands) > 0 {
+				arr, _ := operands[0].(pdfArray)
+				for _, v := range arr {
+					if n, ok := v.(float64); ok {
+						t.advance(-n / 1000 * t.fontSize * t.hScale)
+					} else {
+						t.show(v)
+					}
+				}
+			}
+		case "Do":
+			if len(operands) > 0 {
+				t.form(resources, operands[0], depth)
+			}
+		case "BI":
+			// skip inline images
+			if i := bytes.Index(contents[l.pos:], []byte("EI")); i >= 0 {
+				l.pos += i + 2
+			} else {
+				return
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+func (t *pdfTextWriter) loadFont(resources pdfDict, name interface{}) *pdfFont {
+	ref := t.f.dict(resources["Font"])[pdfNameOf(name)]
+	key := ref
+	if _, ok := ref.(pdfRef); !ok {
+		// direct font dictionaries can't be used as map keys
+		key = name
+	}
+	if font, ok := t.fonts[key]; ok {
+		return font
+	}
+	font := t.f.loadFont(ref)
+	t.fonts[key] = font
+	return font
+}
+
+func pdfNameOf(v interface{}) pdfName {
+	n, _ := v.(pdfName)
+	return n
+}
+
+func (t *pdfTextWriter) form(resources pdfDict, name interface{}, depth int) {
+	s, ok := t.f.resolve(t.f.dict(resources["XObject"])[pdfNameOf(name)]).(pdfStream)
+	if !ok || s.dict["Subtype"] != pdfName("Form") {
+		return
+	}
+	saved := t.pdfGraphicsState
+	if m := t.f.array(s.dict["Matrix"]); len(m) == 6 {
+		var mat pdfMatrix
+		for i := range mat {
+			mat[i] = t.f.number(m[i])
+		}
+		t.ctm = mat.mul(t.ctm)
+	}
+	formResources := t.f.dict(s.dict["Resources"])
+	if formResources == nil {
+		formResources = resources
+	}
+	t.run(t.f.decode(s), formResources, depth+1)
+	t.pdfGraphicsState = saved
+}
+
+func (t *pdfTextWriter) moveLine(tx, ty float64) {
+	t.tlm = pdfMatrix{1, 0, 0, 1, tx, ty}.mul(t.tlm)
+	t.tm = t.tlm
+}
+
+func (t *pdfTextWriter) advance(tx float64) {
+	t.tm = pdfMatrix{1, 0, 0, 1, tx, 0}.mul(t.tm)
+}
+
+// position returns the current point and font size in device space.
+func (t *pdfTextWriter) position() (x, y, size float64) {
+	m := pdfMatrix{1, 0, 0, 1, 0, t.rise}.mul(t.tm).mul(t.ctm)
+	scale := math.Sqrt(math.Abs(m[0]*m[3] - m[1]*m[2]))
+	return m[4], m[5], math.Abs(t.fontSize) * scale
+}
+
+func (t *pdfTextWriter) show(v interface{}) {
+	s, ok := v.(pdfString)
+	if !ok {
+		return
+	}
+	for _, g := range t.font.glyphs(s) {
+		x, y, size := t.position()
+		if g.text != "" {
+			t.place(x, y, size)
+			t.buf.WriteString(g.text)
+		}
+		tx := g.width/1000*t.fontSize + t.charSpace
+		if g.space {
+			tx += t.wordSpace
+		}
+		t.advance(tx * t.hScale)
+		if g.text != "" {
+			t.lastX, _, _ = t.position()
+		}
+	}
+}
+
+// place starts a new line or inserts a space when text is shown at (x, y)
+// away from where the last text ended.
+func (t *pdfTextWriter) place(x, y, size float64) {
+	if size == 0 {
+		size = 10
+	}
+	if !t.started {
+		t.started = true
+	} else if dy := t.lastY - y; math.Abs(dy) > 0.5*math.Min(size, t.lastSize) {
+		t.buf.WriteString("\n")
+		// a larger gap between lines separates paragraphs
+		if dy > 1.8*t.lastSize {
+			t.buf.WriteString("\n")
+		}
+	} else if x-t.lastX > 0.15*size {
+		s := t.buf.String()
+		if !strings.HasSuffix(s, " ") {
+			t.buf.WriteString(" ")
+		}
+	}
+	t.lastY, t.lastSize = y, size
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const (
+	testCatalog = "<< /Type /Catalog /Pages 2 0 R >>"
+	testPages   = "<< /Type /Pages /Kids [3 0 R] /Count 1 >>"
+	testPage    = "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R" +
+		" /Resources << /Font << /F1 5 0 R >> >> >>"
+	testContents = "BT /F1 12 Tf 72 720 Td (Sample Input) Tj 0 -14 Td (1 2) Tj ET"
+	testFont     = "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>"
+)
+
+func flate(data string) string {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write([]byte(data))
+	w.Close()
+	return buf.String()
+}
+
+func stream(dict, data string) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+// buildPdf numbers objs from 1 and ends the file with trailer, which is an
+// xref table and trailer, or an xref stream when it is "xref stream". Empty
+// objs are in the object stream of the file.
+func buildPdf(objs []string, trailer string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.5\n")
+	offsets := make([]int, len(objs))
+	stm := 0
+	for i, obj := range objs {
+		if obj == "" {
+			continue
+		}
+		if strings.Contains(obj, "/Type /ObjStm") {
+			stm = i + 1
+		}
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	start := buf.Len()
+	switch trailer {
+	case "xref table":
+		fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objs)+1)
+		for _, off := range offsets {
+			fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+		}
+		fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\n", len(objs)+1)
+	case "xref stream":
+		// type, offset or object stream, index in the object stream
+		var rows bytes.Buffer
+		rows.Write([]byte{0, 0, 0, 0, 0})
+		index := 0
+		for i, off := range offsets {
+			if objs[i] == "" {
+				rows.Write([]byte{2, 0, byte(stm >> 8), byte(stm), byte(index)})
+				index++
+			} else {
+				rows.Write([]byte{1, byte(off >> 16), byte(off >> 8), byte(off), 0})
+			}
+		}
+		rows.Write([]byte{1, byte(start >> 16), byte(start >> 8), byte(start), 0})
+		n := len(objs) + 1
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", n, stream(
+			fmt.Sprintf("/Type /XRef /Size %d /W [1 3 1] /Root 1 0 R /Filter /FlateDecode", n+1),
+			flate(rows.String())))
+	}
+	fmt.Fprintf(&buf, "startxref\n%d\n%%%%EOF\n", start)
+	return buf.Bytes()
+}
+
+// objStm packs objs, numbered from first, in an object stream.
+func objStm(first int, objs ...string) string {
+	var header, body strings.Builder
+	for i, obj := range objs {
+		fmt.Fprintf(&header, "%d %d ", first+i, body.Len())
+		body.WriteString(obj + "\n")
+	}
+	data := header.String() + body.String()
+	return stream(fmt.Sprintf("/Type /ObjStm /N %d /First %d /Filter /FlateDecode", len(objs), header.Len()),
+		flate(data))
+}
+
+func TestReadPdfText(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"xref table", buildPdf([]string{
+			testCatalog, testPages, testPage, stream("", testContents), testFont,
+		}, "xref table")},
+		{"flate", buildPdf([]string{
+			testCatalog, testPages, testPage, stream("/Filter /FlateDecode", flate(testContents)), testFont,
+		}, "xref table")},
+		{"object stream", buildPdf([]string{
+			"", "", "", stream("/Filter /FlateDecode", flate(testContents)), testFont,
+			objStm(1, testCatalog, testPages, testPage),
+		}, "xref stream")},
+		{"wrong lengths", bytes.Replace(buildPdf([]string{
+			testCatalog, testPages, testPage, stream("", testContents), testFont,
+		}, "xref table"), []byte(fmt.Sprintf("/Length %d", len(testContents))), []byte("/Length 999"), 1)},
+		{"bad font", buildPdf([]string{
+			testCatalog, testPages, testPage, stream("", testContents), "<< /Type /Font ) >>",
+		}, "xref table")},
+		{"bad object", buildPdf([]string{
+			testCatalog, testPages, testPage, stream("", testContents), testFont,
+			stream("/Filter /FlateDecode", "not flate"), "<< /Broken [1 2",
+			objStm(9, "1 2 3"), stream("/Type /ObjStm /N 3 /First 0", "x y"),
+		}, "xref table")},
+		{"bad content stream", buildPdf([]string{
+			testCatalog, testPages,
+			"<< /Type /Page /Contents [4 0 R 6 0 R] /Resources << /Font << /F1 5 0 R >> >> >>",
+			stream("", testContents), testFont, stream("/Filter /LZWDecode", "garbage"),
+		}, "xref table")},
+	}
+	for _, test := range tests {
+		text, err := readPdfText(test.data)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if !strings.Contains(text, "Sample Input\n1 2\n") {
+			t.Errorf("%s: got %q", test.name, text)
+		}
+	}
+}
+
+func TestReadPdfTextErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not a pdf", []byte("<html></html>")},
+		{"no catalog", buildPdf([]string{"(not a dictionary)", testPages}, "xref table")},
+		{"truncated", buildPdf([]string{testCatalog, testPages, testPage}, "xref table")[:40]},
+		{"encrypted", bytes.Replace(buildPdf([]string{
+			testCatalog, testPages, testPage, stream("", testContents), testFont,
+		}, "xref table"), []byte("/Root 1 0 R"), []byte("/Root 1 0 R /Encrypt 5 0 R"), 1)},
+	}
+	for _, test := range tests {
+		if text, err := readPdfText(test.data); err == nil {
+			t.Errorf("%s: got %q, want an error", test.name, text)
+		}
+	}
+}
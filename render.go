@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	humanize "github.com/dustin/go-humanize"
+)
+
+const indent = "       "
+
+// wrap breaks text into lines of at most width runes, without breaking words.
+func wrap(text string, width int) []string {
+	var lines []string
+	var line strings.Builder
+	n := 0
+	for _, word := range strings.Fields(text) {
+		l := utf8.RuneCountInString(word)
+		if n > 0 && n+1+l > width {
+			lines = append(lines, line.String())
+			line.Reset()
+			n = 0
+		}
+		if n > 0 {
+			line.WriteByte(' ')
+			n++
+		}
+		line.WriteString(word)
+		n += l
+	}
+	if n > 0 {
+		lines = append(lines, line.String())
+	}
+	return lines
+}
+
+// paragraphs splits text by blank lines.
+func paragraphs(text string) [][]string {
+	var paras [][]string
+	var para []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			if len(para) != 0 {
+				paras = append(paras, para)
+				para = nil
+			}
+		} else {
+			para = append(para, strings.TrimRight(line, " "))
+		}
+	}
+	if len(para) != 0 {
+		paras = append(paras, para)
+	}
+	return paras
+}
+
+// isPreformatted guesses whether a paragraph is a table or a figure, which
+// are made of short lines, rather than prose hard-wrapped by the PDF.
+func isPreformatted(para []string) bool {
+	if len(para) < 2 {
+		return false
+	}
+	short := 0
+	for _, line := range para {
+		if utf8.RuneCountInString(strings.TrimSpace(line)) < 30 {
+			short++
+		}
+	}
+	return short*4 >= len(para)*3
+}
+
+// joinLines joins the lines of a paragraph, and undoes the hyphenation at
+// line ends.
+func joinLines(para []string) string {
+	var buf strings.Builder
+	hyphenated := false
+	for i, line := range para {
+		line = strings.TrimSpace(line)
+		if i > 0 && !hyphenated {
+			buf.WriteByte(' ')
+		}
+		hyphenated = false
+		if i+1 < len(para) && len(line) > 1 && strings.HasSuffix(line, "-") {
+			next, _ := utf8.DecodeRuneInString(strings.TrimSpace(para[i+1]))
+			if unicode.IsLower(next) {
+				line = line[:len(line)-1]
+				hyphenated = true
+			}
+		}
+		buf.WriteString(line)
+	}
+	return buf.String()
+}
+
+// block is a paragraph of a statement section.
+type block struct {
+	// Preformatted blocks keep their lines, others are reflowed.
+	pre   bool
+	lines []string
+}
+
+// sectionBlocks splits the body of a section into blocks. Sample sections
+// are a single preformatted block.
+func sectionBlocks(s section) []block {
+	if strings.HasPrefix(s.Heading, "Sample") {
+		if s.Body == "" {
+			return nil
+		}
+		return []block{{true, strings.Split(s.Body, "\n")}}
+	}
+	var blocks []block
+	for _, para := range paragraphs(s.Body) {
+		if isPreformatted(para) {
+			blocks = append(blocks, block{true, para})
+		} else {
+			blocks = append(blocks, block{false, []string{joinLines(para)}})
+		}
+	}
+	return blocks
+}
+
+func sectionTitle(s section) string {
+	if s.Heading == "" {
+		return "Description"
+	}
+	return s.Heading
+}
+
+func statistics(info problemInfo) []string {
+	accepted := humanize.Bytes(uint64(float32(info.TotalSubmissions) * info.Percentage / 100))
+	submissions := humanize.Bytes(uint64(info.TotalSubmissions))
+	stats := []string{
+		fmt.Sprintf("Rate: %.1f %%", info.Percentage),
+		fmt.Sprintf("Total Accepted: %s", accepted[:len(accepted)-1]),
+		fmt.Sprintf("Total Submissions: %s", submissions[:len(submissions)-1]),
+	}
+	if info.DACU != 0 {
+		stats = append(stats, fmt.Sprintf("Distinct Accepted Users: %d", info.DACU))
+	}
+	if info.TimeLimit != 0 {
+		stats = append(stats, fmt.Sprintf("Time Limit: %s", info.TimeLimit))
+	}
+	if info.BestRuntime != 0 {
+		stats = append(stats, fmt.Sprintf("Best Runtime: %s", info.BestRuntime))
+	}
+	if info.Status == problemSpecialJudge {
+		stats = append(stats, "Special judge")
+	}
+	if !info.available() {
+		stats = append(stats, "Not available for submission")
+	}
+	return stats
+}
+
+// renderStatement formats a problem like a man page for a terminal of the
+// given width. Sample blocks are kept as they are, other paragraphs are
+// reflowed.
+func renderStatement(info problemInfo, text string, width int) string {
+	var buf strings.Builder
+	textWidth := width - len(indent)
+	if textWidth < 20 {
+		textWidth = 20
+	}
+
+	title := fmt.Sprintf("%d - %s", info.ID, info.Title)
+	if pad := (width - utf8.RuneCountInString(title)) / 2; pad > 0 {
+		buf.WriteString(strings.Repeat(" ", pad))
+	}
+	buf.WriteString(colored(title, white, bold))
+	buf.WriteString("\n\n")
+
+	buf.WriteString(colored("Statistics", white, bold) + "\n")
+	for _, s := range statistics(info) {
+		buf.WriteString(indent + "* " + s + "\n")
+	}
+
+	for _, s := range splitSections(text) {
+		buf.WriteString("\n" + colored(sectionTitle(s), white, bold) + "\n")
+		for i, b := range sectionBlocks(s) {
+			if i > 0 {
+				buf.WriteString("\n")
+			}
+			lines := b.lines
+			if !b.pre {
+				lines = wrap(lines[0], textWidth)
+			}
+			for _, line := range lines {
+				buf.WriteString(strings.TrimRight(indent+line, " ") + "\n")
+			}
+		}
+	}
+	return buf.String()
+}
+
+var markdownEscaper = strings.NewReplacer(
+	"\\", "\\\\", "*", "\\*", "_", "\\_", "`", "\\`", "<", "&lt;", "[", "\\[", "]", "\\]",
+)
+
+func renderMarkdown(info problemInfo, text string) string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "# %d - %s\n\n", info.ID, markdownEscaper.Replace(info.Title))
+	buf.WriteString("## Statistics\n\n")
+	for _, s := range statistics(info) {
+		buf.WriteString("- " + s + "\n")
+	}
+	fmt.Fprintf(&buf, "- Link: <%s>\n", info.url())
+
+	for _, s := range splitSections(text) {
+		fmt.Fprintf(&buf, "\n## %s\n", sectionTitle(s))
+		for _, b := range sectionBlocks(s) {
+			buf.WriteString("\n")
+			if b.pre {
+				buf.WriteString("```\n" + strings.Join(b.lines, "\n") + "\n```\n")
+			} else {
+				buf.WriteString(markdownEscaper.Replace(b.lines[0]) + "\n")
+			}
+		}
+	}
+	return buf.String()
+}
+
+func renderHTML(info problemInfo, text string) string {
+	var buf strings.Builder
+	title := html.EscapeString(fmt.Sprintf("%d - %s", info.ID, info.Title))
+	fmt.Fprintf(&buf, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n</head>\n<body>\n", title)
+	fmt.Fprintf(&buf, "<h1>%s</h1>\n", title)
+	buf.WriteString("<h2>Statistics</h2>\n<ul>\n")
+	for _, s := range statistics(info) {
+		fmt.Fprintf(&buf, "<li>%s</li>\n", html.EscapeString(s))
+	}
+	link := html.EscapeString(info.url())
+	fmt.Fprintf(&buf, "<li>Link: <a href=\"%s\">%s</a></li>\n</ul>\n", link, link)
+
+	for _, s := range splitSections(text) {
+		fmt.Fprintf(&buf, "<h2>%s</h2>\n", sectionTitle(s))
+		for _, b := range sectionBlocks(s) {
+			if b.pre {
+				fmt.Fprintf(&buf, "<pre>%s</pre>\n", html.EscapeString(strings.Join(b.lines, "\n")))
+			} else {
+				fmt.Fprintf(&buf, "<p>%s</p>\n", html.EscapeString(b.lines[0]))
+			}
+		}
+	}
+	buf.WriteString("</body>\n</html>\n")
+	return buf.String()
+}
+
+// page prints text through $PAGER if it does not fit in the terminal.
+func page(text string) {
+	_, height := terminalSize()
+	if !isTerminal || strings.Count(text, "\n") < height {
+		fmt.Print(text)
+		return
+	}
+	pager := strings.Fields(os.Getenv("PAGER"))
+	if len(pager) == 0 {
+		pager = []string{"less", "-R"}
+	}
+	cmd := exec.Command(pager[0], pager[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); !ok {
+			// the pager is not installed
+			fmt.Print(text)
+		}
+	}
+}
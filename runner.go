@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// The steps of judging a program, shared by `uva test` and the judge server.
+// Commands run in dir, or in the current directory if it is empty.
+
+// Verdicts, named like the judge does.
+const (
+	accepted          = "Accepted"
+	wrongAnswer       = "Wrong answer"
+	presentationError = "Presentation error"
+	compilationError  = "Compilation error"
+	runtimeError      = "Runtime error"
+	timeLimitExceeded = "Time limit exceeded"
+)
+
+// compileSource runs the compile command of the language, if it has one.
+// It returns what the compiler printed, and whether compilation failed.
+func compileSource(test testConfig, vars cmdVars, dir string) (out string, failed bool) {
+	compile := renderCmd(test.Compile, vars)
+	if compile == nil {
+		return "", false
+	}
+	compile.Dir = dir
+	data, err := compile.CombinedOutput()
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			// a non-zero exit code means compilation failed
+			return string(data), true
+		}
+		panic(err)
+	}
+	return string(data), false
+}
+
+// testCase is an input and its answer. Answer is empty when the output is
+// only shown.
+type testCase struct {
+	Name          string
+	Input, Answer string
+}
+
+type runResult struct {
+	output, stderr string
+	runTime        time.Duration
+	timedOut       bool
+	// exitCode is not 0 if the program crashed
+	exitCode int
+}
+
+// runProgram runs the program on input, and kills it after limit if it is
+// not 0.
+func runProgram(test testConfig, vars cmdVars, dir, input string, limit time.Duration) runResult {
+	run := renderCmd(test.Run, vars)
+	run.Dir = dir
+	run.Stdin = strings.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	run.Stdout = &stdout
+	run.Stderr = &stderr
+
+	start := time.Now()
+	if err := run.Start(); err != nil {
+		panic(err)
+	}
+	var timer *time.Timer
+	if limit > 0 {
+		timer = time.AfterFunc(limit, func() { run.Process.Kill() })
+	}
+	err := run.Wait()
+	r := runResult{
+		output:  stdout.String(),
+		stderr:  stderr.String(),
+		runTime: time.Since(start),
+		// the timer has fired and killed the program
+		timedOut: timer != nil && !timer.Stop(),
+	}
+	if err != nil {
+		ee, ok := err.(*exec.ExitError)
+		if !ok {
+			panic(err)
+		}
+		r.exitCode = -1
+		if status, ok := ee.Sys().(syscall.WaitStatus); ok && status.ExitStatus() > 0 {
+			r.exitCode = status.ExitStatus()
+		}
+	}
+	return r
+}
+
+// compareOutput checks the output with the checker of the problem, or
+// compares it with the answer. It returns a verdict, and the checker's
+// message or a diff if the output is wrong.
+func compareOutput(prob problemConfig, vars cmdVars, input, output, answer string, byteByByte bool) (verdict, msg string) {
+	if len(prob.Checker) != 0 {
+		if msg, ok := check(prob, vars, input, output, answer); !ok {
+			return wrongAnswer, msg
+		}
+		return accepted, ""
+	}
+	sep := " "
+	if byteByByte {
+		sep = ""
+	}
+	if prob.ignoreCase {
+		answer, output = strings.ToLower(answer), strings.ToLower(output)
+	}
+	d, same := diff(answer, output, yes+" Answer", no+" Output", sep, prob.Tolerance)
+	if same {
+		return accepted, ""
+	}
+	if sameTokens(answer, output, prob.Tolerance) {
+		if prob.ignoreSpace {
+			return accepted, ""
+		}
+		return presentationError, d
+	}
+	return wrongAnswer, d
+}
+
+// sameTokens reports whether two texts have the same words, regardless of
+// spaces and line breaks.
+func sameTokens(text1, text2 string, tolerance float64) bool {
+	words1, words2 := strings.Fields(text1), strings.Fields(text2)
+	if len(words1) != len(words2) {
+		return false
+	}
+	for i := range words1 {
+		if !sameWord(words1[i], words2[i], tolerance) {
+			return false
+		}
+	}
+	return true
+}
+
+// check runs a custom checker on the output, and returns what the checker
+// printed and whether it accepted the output.
+func check(prob problemConfig, vars cmdVars, input, output, answer string) (msg string, ok bool) {
+	dir, err := ioutil.TempDir("", "uva-check")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+	files := checkerVars(dir+"/input", dir+"/output", dir+"/answer")
+	for name, text := range map[string]string{"input": input, "output": output, "answer": answer} {
+		if err := ioutil.WriteFile(files[name], []byte(text), 0666); err != nil {
+			panic(err)
+		}
+	}
+	if !prob.validator {
+		out, err := renderCmd(prob.Checker, vars.with(files)).CombinedOutput()
+		if err != nil {
+			if _, ok := err.(*exec.ExitError); ok {
+				return string(out), false
+			}
+			panic(err)
+		}
+		return string(out), true
+	}
+
+	files["feedback"] = dir + "/feedback"
+	if err := os.Mkdir(files["feedback"], 0755); err != nil {
+		panic(err)
+	}
+	cmd := renderCmd(prob.Checker, vars.with(files))
+	cmd.Stdin = strings.NewReader(output)
+	out, err := cmd.CombinedOutput()
+	// the validator explains a wrong answer in judgemessage.txt
+	feedback, _ := ioutil.ReadFile(files["feedback"] + "/judgemessage.txt")
+	msg = string(out) + string(feedback)
+	code := 0
+	if ee, ok := err.(*exec.ExitError); ok {
+		code = ee.ExitCode()
+	} else if err != nil {
+		panic(err)
+	}
+	switch code {
+	case 42:
+		return msg, true
+	case 43:
+		return msg, false
+	}
+	panic(fmt.Sprintf("the output validator exited with code %d instead of 42 or 43: %s", code, msg))
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+// The judge server runs submissions with the compile and run commands of
+// config.yml, against the test data of `uva test`. Its JSON API:
+//
+//	POST /submissions       {"problem": 10041, "language": "cpp", "code": "..."}
+//	                        responds {"id": "1"}
+//	GET  /submissions/{id}  responds {"id": "1", "problem": 10041, "language": "cpp",
+//	                        "status": "Accepted", "runtime": "0.012", "message": ""}
+//
+// The status is "In judge queue", "Compiling" or "Running" until there is a
+// verdict. Submissions are kept in memory only.
+
+// Used when neither config nor uHunt knows the time limit.
+const defaultTimeLimit = 3 * time.Second
+
+// judgeTimeLimit returns the time limit in config, or else the judge's.
+func judgeTimeLimit(prob problemConfig, info problemInfo) time.Duration {
+	if limit := prob.timeLimit(); limit != 0 {
+		return limit
+	}
+	if info.TimeLimit != 0 {
+		return info.TimeLimit
+	}
+	return defaultTimeLimit
+}
+
+const maxCodeSize = 1 << 20
+
+type serverSubmission struct {
+	ID       string `json:"id"`
+	Problem  int    `json:"problem"`
+	Language string `json:"language"`
+	Status   string `json:"status"`
+	Runtime  string `json:"runtime,omitempty"`
+	// Message is the compiler output, or a diff for a wrong answer.
+	Message string `json:"message,omitempty"`
+}
+
+type submissionRequest struct {
+	Problem  int    `json:"problem"`
+	Language string `json:"language"`
+	Code     string `json:"code"`
+}
+
+type judgeServer struct {
+	mu          sync.Mutex
+	submissions map[string]*serverSubmission
+	code        map[string]string
+	queue       chan string
+	lastID      int
+	// problems locks the test caches of each problem
+	problems map[int]*sync.Mutex
+}
+
+func (s *judgeServer) update(id string, f func(sub *serverSubmission)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f(s.submissions[id])
+}
+
+func (s *judgeServer) worker() {
+	for id := range s.queue {
+		s.mu.Lock()
+		sub, code := *s.submissions[id], s.code[id]
+		delete(s.code, id)
+		s.mu.Unlock()
+
+		status, runTime, msg := s.judge(sub, code)
+		s.update(id, func(sub *serverSubmission) {
+			sub.Status, sub.Message = status, msg
+			if runTime != 0 {
+				sub.Runtime = fmt.Sprintf("%.3f", runTime.Seconds())
+			}
+		})
+		fmt.Printf("%s %d %s: %s\n", id, sub.Problem, sub.Language, status)
+	}
+}
+
+// testData returns the tests of a problem. Workers fill the caches of a
+// problem one at a time, so they do not write the same files at once.
+func (s *judgeServer) testData(info problemInfo) (input, answer string) {
+	s.mu.Lock()
+	lock, ok := s.problems[info.ID]
+	if !ok {
+		lock = new(sync.Mutex)
+		s.problems[info.ID] = lock
+	}
+	s.mu.Unlock()
+	lock.Lock()
+	defer lock.Unlock()
+	input, answer = getTestData(info.ID)
+	if answer == "" {
+		input, answer = getSamples(info)
+	}
+	return input, answer
+}
+
+// judge compiles and runs a submission in a temporary directory.
+func (s *judgeServer) judge(sub serverSubmission, code string) (status string, runTime time.Duration, msg string) {
+	defer func() {
+		if err := recover(); err != nil {
+			status, msg = "Can't be judged", fmt.Sprint(err)
+		}
+	}()
+	info := getProblemInfo(sub.Problem)
+	input, answer := s.testData(info)
+	if answer == "" {
+		panic("no test case for this problem")
+	}
+
+	dir, err := ioutil.TempDir("", "uva-judge")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+	file := info.getFileName(sub.Language)
+	if err := ioutil.WriteFile(filepath.Join(dir, file), []byte(code), 0644); err != nil {
+		panic(err)
+	}
+	vars := newCmdVars(file, sub.Problem)
+	test := config.Test[sub.Language]
+
+	s.update(sub.ID, func(sub *serverSubmission) { sub.Status = "Compiling" })
+	if out, failed := compileSource(test, vars, dir); failed {
+		return compilationError, 0, out
+	}
+
+	s.update(sub.ID, func(sub *serverSubmission) { sub.Status = "Running" })
+	prob := config.problem(sub.Problem)
+	r := runProgram(test, vars, dir, input, judgeTimeLimit(prob, info))
+	switch {
+	case r.timedOut:
+		return timeLimitExceeded, r.runTime, ""
+	case r.exitCode != 0:
+		return runtimeError, r.runTime, r.stderr
+	}
+	verdict, msg := compareOutput(prob, vars, input, r.output, answer, true)
+	return verdict, r.runTime, msg
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func httpError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
+
+func (s *judgeServer) submit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpError(w, http.StatusMethodNotAllowed, "use POST to submit")
+		return
+	}
+	var req submissionRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxCodeSize)).Decode(&req); err != nil {
+		httpError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, ok := config.Test[req.Language]; !ok {
+		httpError(w, http.StatusBadRequest, "unsupported language "+req.Language)
+		return
+	}
+	if _, ok := loadProblems()[req.Problem]; !ok {
+		httpError(w, http.StatusBadRequest, fmt.Sprintf("problem %d not found", req.Problem))
+		return
+	}
+
+	s.mu.Lock()
+	s.lastID++
+	id := strconv.Itoa(s.lastID)
+	s.submissions[id] = &serverSubmission{
+		ID:       id,
+		Problem:  req.Problem,
+		Language: req.Language,
+		Status:   "In judge queue",
+	}
+	s.code[id] = req.Code
+	s.mu.Unlock()
+
+	select {
+	case s.queue <- id:
+		writeJSON(w, http.StatusAccepted, map[string]string{"id": id})
+	default:
+		s.update(id, func(sub *serverSubmission) { sub.Status = "Submission error" })
+		httpError(w, http.StatusServiceUnavailable, "the judge queue is full")
+	}
+}
+
+func (s *judgeServer) result(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpError(w, http.StatusMethodNotAllowed, "use GET to see a submission")
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/submissions/")
+	s.mu.Lock()
+	sub, ok := s.submissions[id]
+	var res serverSubmission
+	if ok {
+		res = *sub
+	}
+	s.mu.Unlock()
+	if !ok {
+		httpError(w, http.StatusNotFound, "submission "+id+" not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func judgeServerCommand(c *cli.Context) {
+	loadConfig()
+	workers := c.Int("workers")
+	if workers <= 0 {
+		panic("at least 1 worker is required")
+	}
+	// download the problem list before serving
+	loadProblems()
+	// messages are sent to clients
+	colorEnabled = false
+	// workers download tests at the same time, and their spinners would
+	// overwrite each other and the log
+	spinnerEnabled = false
+	s := &judgeServer{
+		submissions: make(map[string]*serverSubmission),
+		code:        make(map[string]string),
+		problems:    make(map[int]*sync.Mutex),
+		queue:       make(chan string, 100),
+		// IDs go to the local history, so they must not repeat when the
+		// server restarts, nor clash with the judge's
+		lastID: int(time.Now().Unix()),
+	}
+	for i := 0; i < workers; i++ {
+		go s.worker()
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/submissions", s.submit)
+	mux.HandleFunc("/submissions/", s.result)
+	addr := c.String("addr")
+	fmt.Printf("Judging on %s with %d workers\n", colored("http://"+addr, yellow, underline), workers)
+	panic(http.ListenAndServe(addr, mux))
+}
+
+// submitToServer sends a file to the judge server at judge_url in config,
+// and returns the submission ID.
+func submitToServer(pid int, file, lang string) string {
+	code, err := ioutil.ReadFile(file)
+	if err != nil {
+		panic(err)
+	}
+	body, err := json.Marshal(submissionRequest{pid, lang, string(code)})
+	if err != nil {
+		panic(err)
+	}
+	defer spin("Sending code to " + config.JudgeURL)()
+	resp, err := http.Post(strings.TrimSuffix(config.JudgeURL, "/")+"/submissions", "application/json", bytes.NewReader(body))
+	if err != nil {
+		panic(err)
+	}
+	defer resp.Body.Close()
+	var res struct{ ID, Error string }
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		panic(fmt.Errorf("%s: %s", config.JudgeURL, err))
+	}
+	if res.Error != "" {
+		panic("submission rejected: " + res.Error)
+	}
+	return res.ID
+}
+
+// serverResult gets the status of a submission from the judge server.
+func serverResult(sid string) (result, runTime string, err error) {
+	resp, err := http.Get(strings.TrimSuffix(config.JudgeURL, "/") + "/submissions/" + sid)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	var res struct {
+		serverSubmission
+		Error string
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", "", fmt.Errorf("%s: %s", config.JudgeURL, err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return "", "", notFoundError(res.Error)
+	}
+	if res.Error != "" {
+		return "", "", fmt.Errorf("%s", res.Error)
+	}
+	return res.Status, res.Runtime, nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// Headings of the sections in a problem statement.
+var sectionHeadings = []string{"Input", "Output", "Sample Input", "Sample Output"}
+
+type section struct {
+	// Heading is empty for the description before the first heading.
+	Heading string
+	Body    string
+}
+
+// pdfText extracts the text of a PDF with pdftotext when it is available,
+// or with the built-in reader. pdf_reader in the config chooses one.
+func pdfText(file string) string {
+	if reader := config.PdfReader; reader != "native" {
+		if _, err := exec.LookPath("pdftotext"); err == nil {
+			text, err := exec.Command("pdftotext", file, "-").Output()
+			if err == nil {
+				return string(text)
+			}
+			cprintf(magenta, 0, "pdftotext failed (%s), using the built-in PDF reader\n", err)
+		} else if reader == "pdftotext" {
+			cprintf(magenta, 0, "pdftotext not found, using the built-in PDF reader. Install poppler for better output.\n")
+		}
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		panic(err)
+	}
+	text, err := readPdfText(data)
+	if err != nil {
+		panic(fmt.Errorf("can not read %s: %s", file, err))
+	}
+	return text
+}
+
+// statementText extracts the text of a cached problem description.
+func statementText(file string) string {
+	if strings.HasSuffix(file, ".html") {
+		f, err := os.Open(file)
+		if err != nil {
+			panic(err)
+		}
+		defer f.Close()
+		return htmlText(f)
+	}
+	return pdfText(file)
+}
+
+// blockElements start a new paragraph in htmlText.
+var blockElements = map[string]bool{
+	"p": true, "div": true, "pre": true, "blockquote": true, "center": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "li": true, "table": true, "tr": true, "hr": true,
+}
+
+// htmlText converts an HTML problem page to text laid out like the output
+// of pdftotext, so it can be split into sections and rendered the same way.
+func htmlText(r io.Reader) string {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		panic(err)
+	}
+	var buf strings.Builder
+	paragraph := func() {
+		s := strings.TrimRight(buf.String(), " \n")
+		buf.Reset()
+		buf.WriteString(s)
+		if s != "" {
+			buf.WriteString("\n\n")
+		}
+	}
+	atLineStart := func() bool {
+		return buf.Len() == 0 || strings.HasSuffix(buf.String(), "\n")
+	}
+	var walk func(n *html.Node, pre bool)
+	walk = func(n *html.Node, pre bool) {
+		switch n.Type {
+		case html.TextNode:
+			if pre {
+				buf.WriteString(n.Data)
+				return
+			}
+			text := strings.Join(strings.Fields(n.Data), " ")
+			if n.Data != "" && unicode.IsSpace(rune(n.Data[0])) && !atLineStart() {
+				buf.WriteString(" ")
+			}
+			if text == "" {
+				return
+			}
+			buf.WriteString(text)
+			if unicode.IsSpace(rune(n.Data[len(n.Data)-1])) {
+				buf.WriteString(" ")
+			}
+		case html.ElementNode, html.DocumentNode:
+			switch n.Data {
+			case "head", "script", "style":
+				return
+			case "br":
+				s := strings.TrimRight(buf.String(), " ")
+				buf.Reset()
+				buf.WriteString(s + "\n")
+				return
+			}
+			block := blockElements[n.Data]
+			if block {
+				paragraph()
+			}
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				walk(c, pre || n.Data == "pre")
+			}
+			if block {
+				paragraph()
+			}
+		}
+	}
+	for _, n := range doc.Nodes {
+		walk(n, false)
+	}
+	return buf.String()
+}
+
+func isHeading(line string) (heading string, ok bool) {
+	line = strings.TrimSuffix(strings.TrimSpace(line), ":")
+	for _, s := range sectionHeadings {
+		if strings.EqualFold(line, s) {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// splitSections splits the text of a statement by the section headings.
+func splitSections(text string) []section {
+	// pdftotext separates pages with form feeds
+	text = strings.Replace(text, "\f", "\n", -1)
+	sections := []section{{}}
+	var body []string
+	for _, line := range strings.Split(text, "\n") {
+		if heading, ok := isHeading(line); ok {
+			sections[len(sections)-1].Body = trimBlankLines(strings.Join(body, "\n"))
+			sections = append(sections, section{Heading: heading})
+			body = nil
+		} else {
+			body = append(body, line)
+		}
+	}
+	sections[len(sections)-1].Body = trimBlankLines(strings.Join(body, "\n"))
+	return sections
+}
+
+// trimBlankLines removes the leading and trailing blank lines, but keeps the
+// indentation of the first line.
+func trimBlankLines(s string) string {
+	lines := strings.Split(s, "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// extractSamples returns the sample input and output of a statement, or
+// empty strings if there is none.
+func extractSamples(text string) (input, output string) {
+	for _, s := range splitSections(text) {
+		if s.Body == "" {
+			continue
+		}
+		switch s.Heading {
+		case "Sample Input":
+			input = s.Body + "\n"
+		case "Sample Output":
+			output = s.Body + "\n"
+		}
+	}
+	return
+}
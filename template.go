@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// cmdVars holds the placeholders that can be used in the commands of
+// config.yml. {} is kept as an alias of {file} for old configs.
+type cmdVars map[string]string
+
+func newCmdVars(file string, pid int) cmdVars {
+	dir, base := filepath.Split(file)
+	if dir == "" {
+		dir = "."
+	} else {
+		dir = filepath.Clean(dir)
+	}
+	stem := strings.TrimSuffix(base, filepath.Ext(base))
+	out := filepath.Join(dir, stem+".out")
+	if !strings.ContainsRune(out, filepath.Separator) {
+		// exec.Command looks up names without a slash in $PATH
+		out = "." + string(filepath.Separator) + out
+	}
+	return cmdVars{
+		"file": file,
+		"stem": stem,
+		"dir":  dir,
+		"pid":  strconv.Itoa(pid),
+		"out":  out,
+	}
+}
+
+// lookup resolves a single placeholder, which is either a name from vars or
+// env.NAME, optionally followed by a shell-style fallback:
+//
+//	{env.CXX:-g++}  use g++ if $CXX is unset or empty
+//	{env.CXX-g++}   use g++ only if $CXX is unset
+func (vars cmdVars) lookup(expr string) (string, error) {
+	name, fallback, op := expr, "", ""
+	if i := strings.Index(expr, ":-"); i >= 0 {
+		name, fallback, op = expr[:i], expr[i+2:], ":-"
+	} else if i := strings.IndexByte(expr, '-'); i >= 0 {
+		name, fallback, op = expr[:i], expr[i+1:], "-"
+	}
+	if name == "" {
+		name = "file"
+	}
+
+	var value string
+	var set bool
+	if strings.HasPrefix(name, "env.") {
+		key := name[len("env."):]
+		if !validEnvName(key) {
+			return "", fmt.Errorf("invalid environment variable name in {%s}", expr)
+		}
+		value, set = os.LookupEnv(key)
+	} else {
+		var ok bool
+		if value, ok = vars[name]; !ok {
+			names := make([]string, 0, len(vars))
+			for k := range vars {
+				names = append(names, k)
+			}
+			sort.Strings(names)
+			return "", fmt.Errorf("unknown placeholder {%s}, available: {%s} and {env.NAME}",
+				expr, strings.Join(names, "}, {"))
+		}
+		set = true
+	}
+	if op == ":-" && value == "" || op == "-" && !set {
+		return fallback, nil
+	}
+	return value, nil
+}
+
+func validEnvName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		if !(c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || i > 0 && '0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// with returns a copy of vars with more placeholders set.
+func (vars cmdVars) with(more cmdVars) cmdVars {
+	r := make(cmdVars, len(vars)+len(more))
+	for k, v := range vars {
+		r[k] = v
+	}
+	for k, v := range more {
+		r[k] = v
+	}
+	return r
+}
+
+// expand replaces all placeholders in arg. Use {{ and }} for literal braces.
+func (vars cmdVars) expand(arg string) (string, error) {
+	var buf strings.Builder
+	for i := 0; i < len(arg); i++ {
+		c := arg[i]
+		switch {
+		case (c == '{' || c == '}') && i+1 < len(arg) && arg[i+1] == c:
+			buf.WriteByte(c)
+			i++
+		case c == '{':
+			end := strings.IndexByte(arg[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unclosed '{' in %q", arg)
+			}
+			value, err := vars.lookup(arg[i+1 : i+end])
+			if err != nil {
+				return "", err
+			}
+			buf.WriteString(value)
+			i += end
+		case c == '}':
+			return "", fmt.Errorf("unmatched '}' in %q", arg)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String(), nil
+}
+
+func renderCmd(cmd []string, vars cmdVars) *exec.Cmd {
+	if len(cmd) == 0 {
+		return nil
+	}
+	args := make([]string, len(cmd))
+	for i, v := range cmd {
+		arg, err := vars.expand(v)
+		if err != nil {
+			panic(err)
+		}
+		args[i] = arg
+	}
+	return exec.Command(args[0], args[1:]...)
+}
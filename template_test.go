@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestCmdVarsExpand(t *testing.T) {
+	os.Setenv("UVA_TEST_SET", "clang++")
+	os.Setenv("UVA_TEST_EMPTY", "")
+	os.Unsetenv("UVA_TEST_UNSET")
+	vars := newCmdVars("src/main.cpp", 100)
+	tests := []struct {
+		arg, want string
+		err       bool
+	}{
+		{"{file}", "src/main.cpp", false},
+		{"{}", "src/main.cpp", false},
+		{"-o{out}", "-osrc/main.out", false},
+		{"{dir}/{stem}.in", "src/main.in", false},
+		{"{pid}", "100", false},
+		{"{{file}}", "{file}", false},
+		{"{env.UVA_TEST_SET:-g++}", "clang++", false},
+		{"{env.UVA_TEST_EMPTY:-g++}", "g++", false},
+		{"{env.UVA_TEST_EMPTY-g++}", "", false},
+		{"{env.UVA_TEST_UNSET-g++}", "g++", false},
+		{"{unknown}", "", true},
+		{"{env.1X}", "", true},
+		{"{file", "", true},
+		{"file}", "", true},
+	}
+	for _, test := range tests {
+		got, err := vars.expand(test.arg)
+		if (err != nil) != test.err || got != test.want {
+			t.Errorf("expand(%q) = %q, %v, want %q, error %v", test.arg, got, err, test.want, test.err)
+		}
+	}
+	if out := newCmdVars("main.cpp", 0)["out"]; out != "./main.out" {
+		t.Errorf("out of main.cpp = %q, want ./main.out", out)
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+// uHunt serves the problem list and the submissions of users as JSON, see
+// https://uhunt.onlinejudge.org/api. Its problem IDs are the TrueID of
+// problemInfo, and "num" is the ID.
+const uhuntURL = "https://uhunt.onlinejudge.org/api"
+
+// Problem status in uHunt.
+const (
+	problemUnavailable = iota
+	problemNormal
+	problemSpecialJudge
+)
+
+// Verdict codes of uHunt.
+const (
+	verdictSubmissionError = 10
+	verdictCannotBeJudged  = 15
+	verdictInQueue         = 20
+	verdictCompileError    = 30
+	verdictRestricted      = 35
+	verdictRuntimeError    = 40
+	verdictOutputLimit     = 45
+	verdictTimeLimit       = 50
+	verdictMemoryLimit     = 60
+	verdictWrongAnswer     = 70
+	verdictPresentation    = 80
+	verdictAccepted        = 90
+)
+
+var verdictNames = map[int]string{
+	verdictSubmissionError: "Submission error",
+	verdictCannotBeJudged:  "Can't be judged",
+	verdictInQueue:         "In queue",
+	verdictCompileError:    "Compile error",
+	verdictRestricted:      "Restricted function",
+	verdictRuntimeError:    "Runtime error",
+	verdictOutputLimit:     "Output limit exceeded",
+	verdictTimeLimit:       "Time limit exceeded",
+	verdictMemoryLimit:     "Memory limit exceeded",
+	verdictWrongAnswer:     "Wrong answer",
+	verdictPresentation:    "Presentation error",
+	verdictAccepted:        "Accepted",
+}
+
+func verdictName(verdict int) string {
+	if name, ok := verdictNames[verdict]; ok {
+		return name
+	}
+	return fmt.Sprintf("Verdict %d", verdict)
+}
+
+func uhuntGet(path, msg string, v interface{}) error {
+	data, _, err := fetch(uhuntURL+path, msg)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("uHunt %s: %s", path, err)
+	}
+	return nil
+}
+
+// uhuntProblemsInfo downloads the problem list. Each problem is an array of
+// [pid, num, title, dacu, mrun, mmem, nover, sube, noj, inq, ce, rf, re, ole,
+// tle, mle, wa, pe, ac, rtl, status, rej], times in milliseconds.
+func uhuntProblemsInfo() (map[int]problemInfo, error) {
+	var rows [][]interface{}
+	if err := uhuntGet("/p", "Downloading problem list from uHunt", &rows); err != nil {
+		return nil, err
+	}
+	problems := make(map[int]problemInfo, len(rows))
+	for _, row := range rows {
+		if len(row) < 21 {
+			return nil, fmt.Errorf("uHunt /p: malformed problem %v", row)
+		}
+		num := func(i int) int {
+			f, _ := row[i].(float64)
+			return int(f)
+		}
+		title, _ := row[2].(string)
+		var total int
+		// submission error ... accepted
+		for i := 7; i <= 18; i++ {
+			total += num(i)
+		}
+		p := problemInfo{
+			Title:            title,
+			ID:               num(1),
+			TrueID:           num(0),
+			TotalSubmissions: total,
+			DACU:             num(3),
+			BestRuntime:      time.Duration(num(4)) * time.Millisecond,
+			TimeLimit:        time.Duration(num(19)) * time.Millisecond,
+			Status:           num(20),
+		}
+		if total != 0 {
+			p.Percentage = float32(num(18)) * 100 / float32(total)
+		}
+		problems[p.ID] = p
+	}
+	return problems, nil
+}
+
+// fetchProblems downloads the problem list from source, uhunt or html,
+// falling back to the judge's web pages if uHunt is down.
+func (j *onlineJudge) fetchProblems(source string) map[int]problemInfo {
+	if j.uhunt && source != "html" {
+		problems, err := uhuntProblemsInfo()
+		if err == nil {
+			return problems
+		}
+		cprintf(magenta, 0, "uHunt failed (%s), downloading the problem list from %s\n", err, j.title)
+	}
+	return j.crawlProblems()
+}
+
+type uhuntSubmission struct {
+	ID        int
+	TrueID    int
+	Verdict   int
+	Runtime   time.Duration
+	Submitted time.Time
+}
+
+type uhuntUser struct {
+	Name, Username string
+	Submissions    []uhuntSubmission
+}
+
+// uhuntUserInfo downloads all submissions of a user.
+func uhuntUserInfo(username string) uhuntUser {
+	var uid int
+	if err := uhuntGet("/uname2uid/"+username, "Looking up "+username, &uid); err != nil {
+		panic(err)
+	}
+	if uid == 0 {
+		panic(fmt.Sprintf("user %s not found on uHunt", username))
+	}
+	var resp struct {
+		Name  string
+		Uname string
+		// [sid, pid, verdict, runtime, submit time, language, rank]
+		Subs [][]int64
+	}
+	if err := uhuntGet(fmt.Sprintf("/subs-user/%d", uid), "Downloading submissions", &resp); err != nil {
+		panic(err)
+	}
+	user := uhuntUser{Name: resp.Name, Username: resp.Uname}
+	for _, s := range resp.Subs {
+		if len(s) < 5 {
+			continue
+		}
+		user.Submissions = append(user.Submissions, uhuntSubmission{
+			ID:        int(s[0]),
+			TrueID:    int(s[1]),
+			Verdict:   int(s[2]),
+			Runtime:   time.Duration(s[3]) * time.Millisecond,
+			Submitted: time.Unix(s[4], 0),
+		})
+	}
+	sort.Slice(user.Submissions, func(i, j int) bool {
+		return user.Submissions[i].Submitted.Before(user.Submissions[j].Submitted)
+	})
+	return user
+}
+
+// solved returns the TrueIDs of the problems accepted and only tried.
+func (u uhuntUser) solved() (accepted, tried map[int]bool) {
+	accepted = make(map[int]bool)
+	tried = make(map[int]bool)
+	for _, s := range u.Submissions {
+		if s.Verdict == verdictAccepted {
+			accepted[s.TrueID] = true
+		}
+	}
+	for _, s := range u.Submissions {
+		if !accepted[s.TrueID] {
+			tried[s.TrueID] = true
+		}
+	}
+	return
+}
+
+// defaultUsername returns name, or the username of the current profile.
+func defaultUsername(name string) string {
+	if name != "" {
+		return name
+	}
+	if !loggedIn() {
+		panic("username required, or log in with `uva user -l`")
+	}
+	return loadLoginInfo().Username
+}
+
+func stats(c *cli.Context) {
+	user := uhuntUserInfo(defaultUsername(c.Args().First()))
+	accepted, tried := user.solved()
+	counts := make(map[int]int)
+	for _, s := range user.Submissions {
+		counts[s.Verdict]++
+	}
+
+	title := user.Username
+	if user.Name != "" {
+		title += " (" + user.Name + ")"
+	}
+	fmt.Println(colored(title, yellow, bold))
+	fmt.Printf("Solved: %s, tried but not solved: %d\n",
+		colored(fmt.Sprint(len(accepted)), cyan, bold), len(tried))
+	ratio := 0.0
+	if n := len(user.Submissions); n != 0 {
+		ratio = float64(counts[verdictAccepted]) * 100 / float64(n)
+	}
+	fmt.Printf("Submissions: %d, AC ratio: %.1f %%\n\n", len(user.Submissions), ratio)
+
+	verdicts := make([]int, 0, len(counts))
+	for v := range counts {
+		verdicts = append(verdicts, v)
+	}
+	// accepted first
+	sort.Sort(sort.Reverse(sort.IntSlice(verdicts)))
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	for _, v := range verdicts {
+		fmt.Fprintf(w, "%s\t%6d\n", verdictName(v), counts[v])
+	}
+	w.Flush()
+}
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 	"time"
+
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 const (
@@ -23,15 +28,75 @@ const (
 	no  = "✘"
 )
 
+// Colors and spinners are disabled when stdout is not a terminal, or when
+// NO_COLOR is set.
+var (
+	isTerminal   = terminal.IsTerminal(int(os.Stdout.Fd()))
+	colorEnabled = isTerminal && os.Getenv("NO_COLOR") == ""
+	// spinnerEnabled is turned off where several goroutines may spin.
+	spinnerEnabled = isTerminal
+)
+
+// Prompts are skipped when stdin is not a terminal, like in CI or when the
+// input is piped from a script.
+var (
+	stdinIsTerminal = terminal.IsTerminal(int(os.Stdin.Fd()))
+	stdin           = bufio.NewReader(os.Stdin)
+)
+
+// readLine reads a line from stdin, without the line break.
+func readLine(prompt string) string {
+	if stdinIsTerminal {
+		fmt.Print(prompt)
+	}
+	line, err := stdin.ReadString('\n')
+	if err == io.EOF && line == "" {
+		panic("unexpected end of input")
+	} else if err != nil && err != io.EOF {
+		panic(err)
+	}
+	return strings.TrimRight(line, "\r\n")
+}
+
+// readPassword reads a line from stdin, without echo on a terminal.
+func readPassword(prompt string) string {
+	if !stdinIsTerminal {
+		return readLine(prompt)
+	}
+	fmt.Print(prompt)
+	password, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Print("\n")
+	if err != nil {
+		panic(err)
+	}
+	return string(password)
+}
+
 func colored(s string, color int, attr int) string {
+	if !colorEnabled {
+		return s
+	}
 	return fmt.Sprintf("\033[%d;%dm%s\033[0m", attr, color, s)
 }
 
+// terminalSize falls back to 80x24 when stdout is not a terminal.
+func terminalSize() (width, height int) {
+	if isTerminal {
+		if w, h, err := terminal.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
+			return w, h
+		}
+	}
+	return 80, 24
+}
+
 func cprintf(color int, attr int, format string, a ...interface{}) {
 	fmt.Printf(colored(format, color, attr), a...)
 }
 
 func spin(text string) func() {
+	if !spinnerEnabled {
+		return func() {}
+	}
 	dots := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
 	for i := 0; i < len(dots); i++ {
 		dots[i] = colored(dots[i], blue, 0)